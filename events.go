@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	corev2 "github.com/sensu/core/v2"
+	"go.uber.org/multierr"
+)
+
+// proxyResult is the outcome of one proxy check for --proxy-events
+type proxyResult struct {
+	Instance string
+	Pxname   string
+	Status   int
+	Output   string
+}
+
+// captureLog runs f and returns what it logged
+func captureLog(f func()) string {
+	var buf bytes.Buffer
+
+	w, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(w)
+		log.SetFlags(flags)
+	}()
+
+	f()
+	return buf.String()
+}
+
+// proxyEntityName makes the proxy entity name from --proxy-entity format:
+// {entity}, {instance} and {pxname} are replaced.
+// By default non-empty of them are joined by dash.
+func proxyEntityName(event *corev2.Event, r proxyResult) string {
+	entity := ""
+	if event != nil && event.Entity != nil {
+		entity = event.Entity.Name
+	} else if hostname, err := os.Hostname(); err == nil {
+		entity = hostname
+	}
+
+	if plugin.ProxyEntity == "" {
+		parts := make([]string, 0, 3)
+		for _, s := range []string{entity, r.Instance, r.Pxname} {
+			if s != "" {
+				parts = append(parts, s)
+			}
+		}
+
+		return strings.Join(parts, "-")
+	}
+
+	return strings.NewReplacer(
+		"{entity}", entity,
+		"{instance}", r.Instance,
+		"{pxname}", r.Pxname,
+	).Replace(plugin.ProxyEntity)
+}
+
+// proxyEvent makes an agent API event for the proxy entity,
+// the check name and handlers are taken from the summary check
+func proxyEvent(event *corev2.Event, r proxyResult, now time.Time) *corev2.Event {
+	check := &corev2.Check{
+		ObjectMeta: corev2.ObjectMeta{
+			Name: plugin.Name,
+		},
+		Status:          uint32(r.Status),
+		Output:          r.Output,
+		Executed:        now.Unix(),
+		ProxyEntityName: proxyEntityName(event, r),
+	}
+
+	if event != nil && event.Check != nil {
+		check.Name = event.Check.Name
+		check.Namespace = event.Check.Namespace
+		check.Handlers = event.Check.Handlers
+		check.Interval = event.Check.Interval
+		check.Ttl = event.Check.Ttl
+	}
+
+	return &corev2.Event{
+		Timestamp: now.Unix(),
+		Check:     check,
+	}
+}
+
+// postEvent sends the event to the agent events API
+func postEvent(ev *corev2.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("event marshal error: %w", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(plugin.AgentAPIURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("agent api error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("agent api error: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// dispatchProxyEvents posts one event per proxy and reports the failed ones
+func dispatchProxyEvents(event *corev2.Event, results []proxyResult) error {
+	now := time.Now()
+
+	var err error
+	sent := 0
+	for _, r := range results {
+		ev := proxyEvent(event, r, now)
+		if err2 := postEvent(ev); err2 != nil {
+			err = multierr.Append(err, fmt.Errorf("%s: %w", ev.Check.ProxyEntityName, err2))
+			continue
+		}
+		sent++
+	}
+
+	log.Printf("Proxy events sent: %d of %d", sent, len(results))
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	corev2 "github.com/sensu/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	defer func() { instances = nil }()
+
+	csv := strings.TrimPrefix(testingCSV, "\n")
+	csv = strings.ReplaceAll(csv, "ipmi_exporter,ctrl01,0,0,0,2,,1683,445808,2145275,,0,,0,0,0,0,UP,", "ipmi_exporter,ctrl01,0,0,0,2,,1683,445808,2145275,,0,,0,0,0,0,DOWN,")
+	csv = strings.ReplaceAll(csv, "ipmi_exporter,ctrl02,0,0,0,2,,1683,445808,2145301,,0,,0,0,0,0,UP,", "ipmi_exporter,ctrl02,0,0,0,2,,1683,445808,2145301,,0,,0,0,0,0,DOWN,")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(csv))
+		assert.NoError(err)
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	events := make(map[string]*corev2.Event)
+	fail := false
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if fail {
+			http.Error(w, "agent is busy", http.StatusServiceUnavailable)
+			return
+		}
+
+		ev := &corev2.Event{}
+		assert.NoError(json.NewDecoder(r.Body).Decode(ev))
+
+		events[ev.Check.ProxyEntityName] = ev
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer agent.Close()
+
+	plugin = Config{
+		PluginConfig: sensu.PluginConfig{Name: "sensu-go-haproxy-check"},
+		URL:          srv.URL + "/haproxy?stats",
+		AllServices:  true,
+		ProxyEvents:  true,
+		AgentAPIURL:  agent.URL + "/events",
+		Thresholds:   Thresholds{WarningPercent: 50, CriticalPercent: 50},
+	}
+
+	event := corev2.FixtureEvent("lb1", "haproxy")
+	event.Check.Handlers = []string{"slack"}
+
+	status, err := checkArgs(event)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+
+	status, err = executeCheck(event)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+
+	assert.Len(events, 4)
+	ev, ok := events["lb1-ipmi_exporter"]
+	if assert.True(ok) {
+		assert.Equal("haproxy", ev.Check.Name)
+		assert.Equal([]string{"slack"}, ev.Check.Handlers)
+		assert.Equal(uint32(sensu.CheckStateCritical), ev.Check.Status)
+		assert.Contains(ev.Check.Output, "UP: 33% of #3 ipmi_exporter services")
+		assert.NotContains(ev.Check.Output, "bk_dashboard_cluster")
+	}
+	assert.Equal(uint32(sensu.CheckStateOK), events["lb1-bk_dashboard_cluster"].Check.Status)
+
+	plugin.ProxyEntity = "{pxname}.{entity}"
+	assert.Equal("px.lb1", proxyEntityName(event, proxyResult{Pxname: "px"}))
+
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	status, err = executeCheck(event)
+	assert.Error(err)
+	assert.Equal(sensu.CheckStateCritical, status)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+var (
+	includeProxy  []*regexp.Regexp
+	excludeProxy  []*regexp.Regexp
+	includeServer []*regexp.Regexp
+	excludeServer []*regexp.Regexp
+)
+
+// compileFilters compiles --include-* and --exclude-* regexes
+func compileFilters() error {
+	var err error
+	for _, f := range []struct {
+		arg      string
+		patterns []string
+		dst      *[]*regexp.Regexp
+	}{
+		{"--include-proxy", plugin.IncludeProxy, &includeProxy},
+		{"--exclude-proxy", plugin.ExcludeProxy, &excludeProxy},
+		{"--include-server", plugin.IncludeServer, &includeServer},
+		{"--exclude-server", plugin.ExcludeServer, &excludeServer},
+	} {
+		*f.dst, err = compileRegexps(f.patterns)
+		if err != nil {
+			return fmt.Errorf("%s error: %w", f.arg, err)
+		}
+	}
+
+	return nil
+}
+
+func compileRegexps(patterns []string) ([]*regexp.Regexp, error) {
+	ret := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, re)
+	}
+
+	return ret, nil
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// proxySelected reports if the proxy should be checked.
+// Excludes win, --service is always checked, includes narrow --all-services.
+func proxySelected(pxname string) bool {
+	if matchAny(excludeProxy, pxname) {
+		return false
+	} else if pxname == plugin.Service {
+		return true
+	} else if len(includeProxy) > 0 {
+		return matchAny(includeProxy, pxname)
+	}
+
+	return plugin.AllServices
+}
+
+// proxyMatches reports if the proxy name passes --include-proxy and --exclude-proxy,
+// for checks which do not select services with --service and --all-services.
+func proxyMatches(pxname string) bool {
+	if matchAny(excludeProxy, pxname) {
+		return false
+	} else if len(includeProxy) > 0 {
+		return matchAny(includeProxy, pxname)
+	}
+
+	return true
+}
+
+// filterServers drops servers skipped by --include-server and --exclude-server,
+// FRONTEND, BACKEND and listener entries are kept.
+func filterServers(svc haproxy.StatService) haproxy.StatService {
+	if len(includeServer) == 0 && len(excludeServer) == 0 {
+		return svc
+	}
+
+	servers := svc.Servers()
+	return svc.Filter(func(s haproxy.StatLine) bool {
+		if _, ok := servers[s.Svname]; !ok {
+			return true
+		}
+		if matchAny(excludeServer, s.Svname) {
+			return false
+		}
+
+		return len(includeServer) == 0 || matchAny(includeServer, s.Svname)
+	})
+}
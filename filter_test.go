@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+func TestProxySelected(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	defer func() { includeProxy, excludeProxy, includeServer, excludeServer = nil, nil, nil, nil }()
+
+	plugin = Config{AllServices: true}
+	assert.NoError(compileFilters())
+	assert.True(proxySelected("bk_api_v1"))
+	assert.True(proxySelected("stats"))
+
+	plugin = Config{
+		IncludeProxy: []string{`^bk_api_.*`},
+		ExcludeProxy: []string{`^stats$`, `_internal$`},
+	}
+	assert.NoError(compileFilters())
+	assert.True(proxySelected("bk_api_v1"))
+	assert.False(proxySelected("bk_api_internal"))
+	assert.False(proxySelected("bk_web"))
+	assert.False(proxySelected("stats"))
+
+	plugin.Service = "bk_web"
+	assert.True(proxySelected("bk_web"))
+
+	plugin = Config{IncludeProxy: []string{`(`}}
+	assert.Error(compileFilters())
+}
+
+func TestFilterServers(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	defer func() { includeProxy, excludeProxy, includeServer, excludeServer = nil, nil, nil, nil }()
+
+	stats := testingStats(t)
+	svc := stats["ipmi_exporter"]
+
+	plugin = Config{}
+	assert.NoError(compileFilters())
+	assert.Len(filterServers(svc), len(svc))
+
+	plugin = Config{ExcludeServer: []string{`02$`}}
+	assert.NoError(compileFilters())
+	ret := filterServers(svc)
+	assert.NotContains(ret, "ctrl02")
+	assert.Contains(ret, "ctrl01")
+	assert.Contains(ret, haproxy.Frontend)
+	assert.Contains(ret, haproxy.Backend)
+
+	plugin = Config{IncludeServer: []string{`^ctrl0[12]$`}, ExcludeServer: []string{`02$`}}
+	assert.NoError(compileFilters())
+	ret = filterServers(svc)
+	assert.Len(ret.Servers(), 1)
+	assert.Contains(ret, "ctrl01")
+	assert.Contains(ret, haproxy.Frontend)
+}
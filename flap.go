@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// Severities for checks which have their own state
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// severityState converts the severity to the check state, warning by default
+func severityState(severity string) int {
+	if severity == SeverityCritical {
+		return sensu.CheckStateCritical
+	}
+
+	return sensu.CheckStateWarning
+}
+
+// flapChecksEnabled reports if any flapping threshold over counter deltas is set
+func (th *Thresholds) flapChecksEnabled() bool {
+	return th.FlapCount > 0 || th.FlapFailCount > 0
+}
+
+// flapDeltas collects chkdown and chkfail increments of servers since the previous run by svname
+func flapDeltas(pxname string, servers haproxy.StatService) map[string]Counters {
+	ret := make(map[string]Counters)
+	if state == nil {
+		return ret
+	}
+
+	for _, l := range servers.Lines() {
+		cur := make(Counters)
+		if v, ok := l.Chkdown.Get(); ok {
+			cur["chkdown"] = v
+		}
+		if v, ok := l.Chkfail.Get(); ok {
+			cur["chkfail"] = v
+		}
+		if len(cur) == 0 {
+			continue
+		}
+
+		deltas, ok := state.Deltas("chk/"+pxname+"/"+l.Svname, cur)
+		if ok {
+			ret[l.Svname] = deltas
+		}
+	}
+
+	return ret
+}
+
+// checkFlapping finds servers which went DOWN or failed health checks since the previous run
+// or changed status recently. Flapping servers get the state of flap severity.
+func checkFlapping(pr *ProxyReport, svc haproxy.StatService, th *Thresholds) int {
+	if !th.flapChecksEnabled() && th.FlapWindow <= 0 {
+		return sensu.CheckStateOK
+	}
+
+	servers := svc.Servers()
+	deltas := flapDeltas(pr.Pxname, servers)
+
+	flapping := make([]string, 0)
+	for _, l := range servers.Lines() {
+		reasons := make([]string, 0, 3)
+		d, ok := deltas[l.Svname]
+		if ok && th.FlapCount > 0 && d["chkdown"] >= int64(th.FlapCount) {
+			reasons = append(reasons, fmt.Sprintf("%d times DOWN", d["chkdown"]))
+		}
+		if ok && th.FlapFailCount > 0 && d["chkfail"] >= int64(th.FlapFailCount) {
+			reasons = append(reasons, fmt.Sprintf("%d failed checks", d["chkfail"]))
+		}
+		if lastchg, ok := l.Lastchg.Get(); ok && th.FlapWindow > 0 && lastchg < int64(th.FlapWindow) {
+			reasons = append(reasons, fmt.Sprintf("%s %ds ago", l.Status, lastchg))
+		}
+
+		if len(reasons) > 0 {
+			flapping = append(flapping, fmt.Sprintf("%s: %s", l.LogName(), strings.Join(reasons, ", ")))
+		}
+	}
+
+	if len(flapping) == 0 {
+		return sensu.CheckStateOK
+	}
+
+	ret := severityState(th.FlapSeverity)
+	log.Printf("Flapping %s: %d of #%d %s servers", strings.ToLower(stateName(ret)), len(flapping), len(servers), pr.Pxname)
+	for _, f := range flapping {
+		log.Printf("\t%s", f)
+	}
+
+	pr.trip("Flapping", ret, fmt.Sprintf("%d of #%d servers are flapping", len(flapping), len(servers)), flapping...)
+	return ret
+}
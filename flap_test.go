@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+func TestCheckFlapping(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{Thresholds: Thresholds{FlapCount: 2, FlapFailCount: 10}}
+
+	defer func(saved *StateStore) { state = saved }(state)
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Unix(1700000000, 0)
+
+	var err error
+	state, err = LoadState(path, now)
+	if !assert.NoError(err) {
+		return
+	}
+
+	svc := testingStats(t)["ipmi_exporter"]
+	pr := &ProxyReport{Pxname: "ipmi_exporter"}
+	assert.Equal(sensu.CheckStateOK, checkFlapping(pr, svc, &plugin.Thresholds))
+	assert.NoError(state.Save())
+
+	state, err = LoadState(path, now.Add(time.Minute))
+	if !assert.NoError(err) {
+		return
+	}
+
+	// ctrl02 went DOWN twice, ctrl03 failed a few checks
+	srv := svc["ctrl02"]
+	srv.Chkdown = haproxy.NewInt64(srv.Chkdown.Int64 + 2)
+	srv.Chkfail = haproxy.NewInt64(srv.Chkfail.Int64 + 6)
+	svc["ctrl02"] = srv
+	srv = svc["ctrl03"]
+	srv.Chkfail = haproxy.NewInt64(srv.Chkfail.Int64 + 3)
+	svc["ctrl03"] = srv
+
+	pr = &ProxyReport{Pxname: "ipmi_exporter"}
+	assert.Equal(sensu.CheckStateWarning, checkFlapping(pr, svc, &plugin.Thresholds))
+	if assert.Len(pr.Tripped, 1) {
+		assert.Equal([]string{"ipmi_exporter/ctrl02[L7OK]: 2 times DOWN"}, pr.Tripped[0].Entries)
+	}
+
+	// status changed recently, with own severity
+	plugin = Config{Thresholds: Thresholds{FlapWindow: 60, FlapSeverity: SeverityCritical}}
+	srv.Lastchg = haproxy.NewInt64(30)
+	svc["ctrl03"] = srv
+
+	pr = &ProxyReport{Pxname: "ipmi_exporter"}
+	assert.Equal(sensu.CheckStateCritical, checkFlapping(pr, svc, &plugin.Thresholds))
+	if assert.Len(pr.Tripped, 1) {
+		assert.Equal([]string{"ipmi_exporter/ctrl03[L7OK]: UP 30s ago"}, pr.Tripped[0].Entries)
+	}
+}
@@ -0,0 +1,110 @@
+package haproxy
+
+import (
+	"reflect"
+)
+
+// aggregateMax are columns which are ids, settings or times, so the maximum is taken.
+// Limits (slim, qlimit, rate_lim, src_ilim) are per process and summed like the current values,
+// so usage percentages of merged lines stay the same as of each process.
+var aggregateMax = map[string]bool{
+	"pid": true, "iid": true, "sid": true, "tracked": true, "type": true,
+	"weight": true, "uweight": true, "act": true, "bck": true,
+	"downtime": true, "throttle": true,
+	"check_code": true, "check_duration": true, "check_rise": true, "check_fall": true, "check_health": true,
+	"agent_code": true, "agent_duration": true, "agent_rise": true, "agent_fall": true, "agent_health": true,
+	"qtime": true, "ctime": true, "rtime": true, "ttime": true,
+	"qtime_max": true, "ctime_max": true, "rtime_max": true, "ttime_max": true,
+}
+
+// aggregateMin are "seconds since" columns, so the latest event is taken
+var aggregateMin = map[string]bool{
+	"lastchg":  true,
+	"lastsess": true,
+}
+
+// aggregateColumns are csv names of NullInt64 columns by StatLine field index
+var aggregateColumns = func() map[int]string {
+	ret := make(map[int]string)
+	t := reflect.TypeOf(StatLine{})
+	for name, fi := range statFieldIndex {
+		if t.Field(fi).Type == reflect.TypeOf(NullInt64{}) {
+			ret[fi] = name
+		}
+	}
+
+	return ret
+}()
+
+// AggregateLines merges lines of the same pxname/svname reported by several processes.
+// Counters and limits are summed, ids and times take the maximum, the worst status wins.
+// Order of the first appearance is kept.
+func AggregateLines(lines []StatLine) []StatLine {
+	ret := make([]StatLine, 0, len(lines))
+	index := make(map[[2]string]int)
+
+	for _, l := range lines {
+		key := [2]string{l.Pxname, l.Svname}
+		idx, ok := index[key]
+		if !ok {
+			index[key] = len(ret)
+			ret = append(ret, l)
+			continue
+		}
+
+		ret[idx] = mergeLines(ret[idx], l)
+	}
+
+	return ret
+}
+
+func mergeLines(a, b StatLine) StatLine {
+	// strings come from the line with the worst status
+	ret := a
+	if statusRank(b.Status) > statusRank(a.Status) {
+		ret = b
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	vr := reflect.ValueOf(&ret).Elem()
+	for fi, name := range aggregateColumns {
+		x := va.Field(fi).Interface().(NullInt64)
+		y := vb.Field(fi).Interface().(NullInt64)
+		if !x.Valid || !y.Valid {
+			if y.Valid {
+				x = y
+			}
+			vr.Field(fi).Set(reflect.ValueOf(x))
+			continue
+		}
+
+		switch {
+		case aggregateMax[name]:
+			x.Int64 = max(x.Int64, y.Int64)
+		case aggregateMin[name]:
+			x.Int64 = min(x.Int64, y.Int64)
+		default:
+			x.Int64 += y.Int64
+		}
+		vr.Field(fi).Set(reflect.ValueOf(x))
+	}
+
+	return ret
+}
+
+// statusRank orders statuses from unknown to the worst
+func statusRank(status string) int {
+	switch {
+	case status == "":
+		return 0
+	case status == "UP" || status == "OPEN" || status == "no check":
+		return 1
+	case status == "DRAIN" || status == "NOLB":
+		return 2
+	case status == "MAINT":
+		return 3
+	default:
+		// DOWN, UP going down and so on
+		return 4
+	}
+}
@@ -0,0 +1,59 @@
+package haproxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateLines(t *testing.T) {
+	assert := assert.New(t)
+
+	lines := []StatLine{
+		{Pxname: "px", Svname: "FRONTEND", Status: "OPEN", Pid: NewInt64(1), Scur: NewInt64(2), Slim: NewInt64(100)},
+		{Pxname: "px", Svname: "srv1", Status: "UP", Pid: NewInt64(1), Scur: NewInt64(1), Lastchg: NewInt64(100), Rtime: NewInt64(10)},
+		{Pxname: "px", Svname: "FRONTEND", Status: "OPEN", Pid: NewInt64(2), Scur: NewInt64(3), Slim: NewInt64(100)},
+		{Pxname: "px", Svname: "srv1", Status: "DOWN", CheckStatus: "L4CON", Pid: NewInt64(2), Scur: NewInt64(4), Lastchg: NewInt64(5), Rtime: NewInt64(20)},
+		{Pxname: "px", Svname: "srv2", Status: "UP", Pid: NewInt64(2)},
+	}
+
+	ret := AggregateLines(lines)
+	if !assert.Len(ret, 3) {
+		return
+	}
+
+	assert.Equal("FRONTEND", ret[0].Svname)
+	assert.Equal(NewInt64(5), ret[0].Scur)
+	assert.Equal(NewInt64(200), ret[0].Slim)
+	assert.Equal(NewInt64(2), ret[0].Pid)
+
+	assert.Equal("srv1", ret[1].Svname)
+	assert.Equal("DOWN", ret[1].Status)
+	assert.Equal("L4CON", ret[1].CheckStatus)
+	assert.Equal(NewInt64(5), ret[1].Scur)
+	assert.Equal(NewInt64(5), ret[1].Lastchg)
+	assert.Equal(NewInt64(20), ret[1].Rtime)
+	assert.False(ret[1].Qcur.Valid)
+
+	assert.Equal("srv2", ret[2].Svname)
+}
+
+func TestAggregateLinesLimitPercentage(t *testing.T) {
+	assert := assert.New(t)
+
+	lines := []StatLine{
+		{Pxname: "px", Svname: "FRONTEND", Pid: NewInt64(1), Scur: NewInt64(600), Slim: NewInt64(1000)},
+		{Pxname: "be", Svname: "srv1", Pid: NewInt64(1), Qcur: NewInt64(6), Qlimit: NewInt64(10)},
+		{Pxname: "px", Svname: "FRONTEND", Pid: NewInt64(2), Scur: NewInt64(600), Slim: NewInt64(1000)},
+		{Pxname: "be", Svname: "srv1", Pid: NewInt64(2), Qcur: NewInt64(6), Qlimit: NewInt64(10)},
+	}
+
+	ret := AggregateLines(lines)
+	if !assert.Len(ret, 2) {
+		return
+	}
+
+	assert.Equal(float32(60), ret[0].SessionLimitPercentage())
+	assert.Equal(NewInt64(12), ret[1].Qcur)
+	assert.Equal(NewInt64(20), ret[1].Qlimit)
+}
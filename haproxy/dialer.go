@@ -0,0 +1,149 @@
+package haproxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout limits the whole runtime API exchange
+const DefaultTimeout = time.Second
+
+// Dialer describes how to reach HAProxy runtime API socket
+type Dialer struct {
+	// Network is one of: unix, tcp, tcp4, tcp6
+	Network string
+	// Address is socket path or host:port
+	Address string
+	// Timeout for the command round trip, DefaultTimeout if zero
+	Timeout time.Duration
+}
+
+// ParseAddress makes a Dialer from the address forms accepted by --socket:
+//
+//	/var/run/haproxy.sock
+//	unix:///var/run/haproxy.sock
+//	unix@/var/run/haproxy.sock
+//	tcp://127.0.0.1:9999
+//	ipv4@127.0.0.1:9999
+//	ipv6@[::1]:9999
+//	ipv6@::1:9999
+func ParseAddress(address string) (*Dialer, error) {
+	if address == "" {
+		return nil, fmt.Errorf("empty address")
+	}
+
+	// HAProxy bind syntax: <family>@<address>
+	if family, addr, ok := strings.Cut(address, "@"); ok && !strings.ContainsAny(family, `/\`) {
+		switch family {
+		case "unix":
+			return unixDialer(addr)
+		case "ipv4":
+			return tcpDialer("tcp4", addr)
+		case "ipv6":
+			return tcpDialer("tcp6", bracketIPv6(addr))
+		default:
+			return nil, fmt.Errorf("unsupported address family: %s", family)
+		}
+	}
+
+	if !strings.Contains(address, "://") {
+		return unixDialer(address)
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("address parse error: %w", err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		return unixDialer(u.Host + u.Path)
+	case "tcp", "tcp4", "tcp6":
+		return tcpDialer(u.Scheme, u.Host)
+	default:
+		return nil, fmt.Errorf("unsupported address scheme: %s", u.Scheme)
+	}
+}
+
+func unixDialer(path string) (*Dialer, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty socket path")
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Dialer{Network: "unix", Address: path}, nil
+}
+
+// bracketIPv6 converts HAProxy "::1:9999" form, where the port follows the last colon, to "[::1]:9999"
+func bracketIPv6(addr string) string {
+	i := strings.LastIndexByte(addr, ':')
+	if strings.HasPrefix(addr, "[") || i < 0 || !strings.Contains(addr[:i], ":") {
+		return addr
+	}
+
+	return "[" + addr[:i] + "]" + addr[i:]
+}
+
+func tcpDialer(network, hostport string) (*Dialer, error) {
+	_, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return nil, fmt.Errorf("address parse error: %w", err)
+	} else if port == "" {
+		return nil, fmt.Errorf("address %s: missing port", hostport)
+	}
+
+	return &Dialer{Network: network, Address: hostport}, nil
+}
+
+// IsUnix reports if the Dialer uses a local UNIX socket
+func (d *Dialer) IsUnix() bool {
+	return d.Network == "unix"
+}
+
+func (d *Dialer) String() string {
+	return fmt.Sprintf("%s://%s", d.Network, d.Address)
+}
+
+func (d *Dialer) timeout() time.Duration {
+	if d.Timeout > 0 {
+		return d.Timeout
+	}
+
+	return DefaultTimeout
+}
+
+// Exec sends one command to the runtime API and returns the whole response
+func (d *Dialer) Exec(cmd string) ([]byte, error) {
+	sock, err := net.DialTimeout(d.Network, d.Address, d.timeout())
+	if err != nil {
+		return nil, fmt.Errorf("socket open error: %w", err)
+	}
+	defer sock.Close()
+
+	// Suggest that IO shouldn't ever reach so long timeout
+	err = sock.SetDeadline(time.Now().Add(d.timeout()))
+	if err != nil {
+		return nil, fmt.Errorf("socket set deadline error: %w", err)
+	}
+
+	_, err = sock.Write([]byte(cmd + "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("socket request error: %w", err)
+	}
+
+	data, err := io.ReadAll(sock)
+	if err != nil {
+		return nil, fmt.Errorf("socket read error: %w", err)
+	}
+
+	return data, nil
+}
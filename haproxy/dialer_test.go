@@ -0,0 +1,137 @@
+package haproxy
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serveRuntimeAPI starts fake runtime API which answers commands from responses map.
+// Returns listener address which is useful for tcp ":0" listeners.
+func serveRuntimeAPI(t *testing.T, network, address string, responses map[string]string) string {
+	t.Helper()
+	assert := assert.New(t)
+
+	serverCtx, serverCf := context.WithCancel(context.TODO())
+	t.Cleanup(serverCf)
+
+	ln, err := net.Listen(network, address)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	go func() {
+		defer ln.Close()
+
+		for {
+			select {
+			case <-serverCtx.Done():
+				t.Log("socket server terminated")
+				return
+
+			default:
+				dl, ok := ln.(interface{ SetDeadline(time.Time) error })
+				if ok {
+					if err := dl.SetDeadline(time.Now().Add(time.Second)); err != nil {
+						assert.NoError(err)
+						return
+					}
+				}
+
+				fd, err := ln.Accept()
+				if err != nil {
+					if os.IsTimeout(err) {
+						continue
+					}
+
+					assert.NoError(err)
+					return
+				}
+
+				go func(c net.Conn) {
+					defer c.Close()
+
+					buf := make([]byte, 1024)
+
+					nr, err := c.Read(buf)
+					assert.NoError(err)
+
+					cmd := strings.TrimSuffix(string(buf[0:nr]), "\n")
+					resp, ok := responses[cmd]
+					assert.True(ok, "unexpected command: %q", cmd)
+					if !ok {
+						resp = "Unknown command.\n"
+					}
+
+					_, err = c.Write([]byte(resp))
+					assert.NoError(err)
+				}(fd)
+			}
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestParseAddress(t *testing.T) {
+	assert := assert.New(t)
+
+	sockPath, err := filepath.Abs("haproxy.sock")
+	assert.NoError(err)
+
+	tests := []struct {
+		address string
+		network string
+		addr    string
+		err     bool
+	}{
+		{"/var/run/haproxy.sock", "unix", "/var/run/haproxy.sock", false},
+		{"haproxy.sock", "unix", sockPath, false},
+		{"unix:///var/run/haproxy.sock", "unix", "/var/run/haproxy.sock", false},
+		{"unix@/var/run/haproxy.sock", "unix", "/var/run/haproxy.sock", false},
+		{"tcp://127.0.0.1:9999", "tcp", "127.0.0.1:9999", false},
+		{"ipv4@127.0.0.1:9999", "tcp4", "127.0.0.1:9999", false},
+		{"ipv6@[::1]:9999", "tcp6", "[::1]:9999", false},
+		{"ipv6@::1:9999", "tcp6", "[::1]:9999", false},
+		{"ipv6@fd00::10:9999", "tcp6", "[fd00::10]:9999", false},
+		{"ipv6@localhost:9999", "tcp6", "localhost:9999", false},
+		{"tcp://127.0.0.1", "", "", true},
+		{"udp://127.0.0.1:9999", "", "", true},
+		{"abns@haproxy", "", "", true},
+		{"", "", "", true},
+	}
+
+	for _, tc := range tests {
+		d, err := ParseAddress(tc.address)
+		if tc.err {
+			assert.Error(err, tc.address)
+			continue
+		}
+
+		if assert.NoError(err, tc.address) {
+			assert.Equal(tc.network, d.Network, tc.address)
+			assert.Equal(tc.addr, d.Address, tc.address)
+		}
+	}
+}
+
+func TestDialerExecTCP(t *testing.T) {
+	assert := assert.New(t)
+
+	addr := serveRuntimeAPI(t, "tcp", "127.0.0.1:0", map[string]string{
+		"show stat": testingCSV,
+	})
+
+	d, err := ParseAddress("ipv4@" + addr)
+	assert.NoError(err)
+
+	stats, _, err := GetStats(d, StatFormatCSV)
+	assert.NoError(err)
+	assert.Len(stats, 4)
+}
@@ -0,0 +1,135 @@
+package haproxy
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// HTTPSource describes how to reach HAProxy stats page (stats uri)
+type HTTPSource struct {
+	// URL of the stats page, e.g. http://127.0.0.1:8404/haproxy?stats
+	URL                string
+	Username           string
+	Password           string
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+	// Timeout for the request, DefaultTimeout if zero
+	Timeout time.Duration
+}
+
+func (s *HTTPSource) String() string {
+	return s.URL
+}
+
+func (s *HTTPSource) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+
+	return DefaultTimeout
+}
+
+// Client makes http.Client with configured TLS options
+func (s *HTTPSource) Client() (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: s.InsecureSkipVerify, //nolint:gosec
+	}
+
+	if s.CAFile != "" {
+		pem, err := os.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("ca file read error: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca file %s: no certificates found", s.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if s.CertFile != "" || s.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate load error: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   s.timeout(),
+	}, nil
+}
+
+// Get requests stats page in the format selected by suffix, e.g. ";csv"
+func (s *HTTPSource) Get(suffix string) ([]byte, error) {
+	client, err := s.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	url := s.URL
+	if !strings.HasSuffix(url, suffix) {
+		url += suffix
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request error: %w", err)
+	}
+
+	if s.Username != "" || s.Password != "" {
+		req.SetBasicAuth(s.Username, s.Password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("http read error: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http request error: %s", resp.Status)
+	}
+
+	return data, nil
+}
+
+// GetStatsHTTP query HAProxy stats page for Stats.
+// Stats page supports only csv and json formats.
+func GetStatsHTTP(s *HTTPSource, format StatFormat) (Stats, []byte, error) {
+	var suffix string
+	switch format {
+	case StatFormatCSV, "":
+		suffix = ";csv"
+	case StatFormatJSON:
+		suffix = ";json"
+	default:
+		return nil, nil, fmt.Errorf("stats page does not support %s format", format)
+	}
+
+	data, err := s.Get(suffix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ParseStats(format, bytes.NewReader(data))
+}
@@ -0,0 +1,73 @@
+package haproxy
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func statsPageHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "stats;csv", r.URL.RawQuery)
+
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, err := w.Write([]byte(testingCSV))
+		assert.NoError(t, err)
+	})
+}
+
+func TestGetStatsHTTP(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(statsPageHandler(t))
+	defer srv.Close()
+
+	stats, _, err := GetStatsHTTP(&HTTPSource{
+		URL:      srv.URL + "/haproxy?stats",
+		Username: "admin",
+		Password: "secret",
+	}, StatFormatCSV)
+	assert.NoError(err)
+	assert.Len(stats, 4)
+
+	_, _, err = GetStatsHTTP(&HTTPSource{
+		URL: srv.URL + "/haproxy?stats",
+	}, StatFormatCSV)
+	assert.ErrorContains(err, "401")
+}
+
+func TestGetStatsHTTPS(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewTLSServer(statsPageHandler(t))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	assert.NoError(os.WriteFile(caFile, caPEM, 0o600))
+
+	src := &HTTPSource{
+		URL:      srv.URL + "/haproxy?stats",
+		Username: "admin",
+		Password: "secret",
+	}
+
+	// unknown CA
+	_, _, err := GetStatsHTTP(src, StatFormatCSV)
+	assert.Error(err)
+
+	src.CAFile = caFile
+	stats, _, err := GetStatsHTTP(src, StatFormatCSV)
+	assert.NoError(err)
+	assert.Len(stats, 4)
+}
@@ -0,0 +1,158 @@
+package haproxy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Info represents process-wide "show info" report
+type Info struct {
+	Name                       string    `info:"Name" json:"name,omitempty"`
+	Version                    string    `info:"Version" json:"version,omitempty"`
+	ReleaseDate                string    `info:"Release_date" json:"release_date,omitempty"`
+	Nbthread                   NullInt64 `info:"Nbthread" json:"nbthread"`
+	Nbproc                     NullInt64 `info:"Nbproc" json:"nbproc"`
+	ProcessNum                 NullInt64 `info:"Process_num" json:"process_num"`
+	Pid                        NullInt64 `info:"Pid" json:"pid"`
+	Uptime                     string    `info:"Uptime" json:"uptime,omitempty"`
+	UptimeSec                  NullInt64 `info:"Uptime_sec" json:"uptime_sec"`
+	MemmaxMB                   NullInt64 `info:"Memmax_MB" json:"memmax_mb"`
+	PoolAllocMB                NullInt64 `info:"PoolAlloc_MB" json:"poolalloc_mb"`
+	PoolUsedMB                 NullInt64 `info:"PoolUsed_MB" json:"poolused_mb"`
+	PoolFailed                 NullInt64 `info:"PoolFailed" json:"poolfailed"`
+	UlimitN                    NullInt64 `info:"Ulimit-n" json:"ulimit_n"`
+	Maxsock                    NullInt64 `info:"Maxsock" json:"maxsock"`
+	Maxconn                    NullInt64 `info:"Maxconn" json:"maxconn"`
+	HardMaxconn                NullInt64 `info:"Hard_maxconn" json:"hard_maxconn"`
+	CurrConns                  NullInt64 `info:"CurrConns" json:"currconns"`
+	CumConns                   NullInt64 `info:"CumConns" json:"cumconns"`
+	CumReq                     NullInt64 `info:"CumReq" json:"cumreq"`
+	MaxSslConns                NullInt64 `info:"MaxSslConns" json:"maxsslconns"`
+	CurrSslConns               NullInt64 `info:"CurrSslConns" json:"currsslconns"`
+	CumSslConns                NullInt64 `info:"CumSslConns" json:"cumsslconns"`
+	Maxpipes                   NullInt64 `info:"Maxpipes" json:"maxpipes"`
+	PipesUsed                  NullInt64 `info:"PipesUsed" json:"pipesused"`
+	PipesFree                  NullInt64 `info:"PipesFree" json:"pipesfree"`
+	ConnRate                   NullInt64 `info:"ConnRate" json:"connrate"`
+	ConnRateLimit              NullInt64 `info:"ConnRateLimit" json:"connratelimit"`
+	MaxConnRate                NullInt64 `info:"MaxConnRate" json:"maxconnrate"`
+	SessRate                   NullInt64 `info:"SessRate" json:"sessrate"`
+	SessRateLimit              NullInt64 `info:"SessRateLimit" json:"sessratelimit"`
+	MaxSessRate                NullInt64 `info:"MaxSessRate" json:"maxsessrate"`
+	SslRate                    NullInt64 `info:"SslRate" json:"sslrate"`
+	SslRateLimit               NullInt64 `info:"SslRateLimit" json:"sslratelimit"`
+	MaxSslRate                 NullInt64 `info:"MaxSslRate" json:"maxsslrate"`
+	SslFrontendKeyRate         NullInt64 `info:"SslFrontendKeyRate" json:"sslfrontendkeyrate"`
+	SslFrontendMaxKeyRate      NullInt64 `info:"SslFrontendMaxKeyRate" json:"sslfrontendmaxkeyrate"`
+	SslFrontendSessionReusePct NullInt64 `info:"SslFrontendSessionReuse_pct" json:"sslfrontendsessionreuse_pct"`
+	SslBackendKeyRate          NullInt64 `info:"SslBackendKeyRate" json:"sslbackendkeyrate"`
+	SslBackendMaxKeyRate       NullInt64 `info:"SslBackendMaxKeyRate" json:"sslbackendmaxkeyrate"`
+	SslCacheLookups            NullInt64 `info:"SslCacheLookups" json:"sslcachelookups"`
+	SslCacheMisses             NullInt64 `info:"SslCacheMisses" json:"sslcachemisses"`
+	CompressBpsIn              NullInt64 `info:"CompressBpsIn" json:"compressbpsin"`
+	CompressBpsOut             NullInt64 `info:"CompressBpsOut" json:"compressbpsout"`
+	CompressBpsRateLim         NullInt64 `info:"CompressBpsRateLim" json:"compressbpsratelim"`
+	Tasks                      NullInt64 `info:"Tasks" json:"tasks"`
+	RunQueue                   NullInt64 `info:"Run_queue" json:"run_queue"`
+	IdlePct                    NullInt64 `info:"Idle_pct" json:"idle_pct"`
+	Node                       string    `info:"node" json:"node,omitempty"`
+	Description                string    `info:"description" json:"description,omitempty"`
+	Stopping                   NullInt64 `info:"Stopping" json:"stopping"`
+	Jobs                       NullInt64 `info:"Jobs" json:"jobs"`
+	UnstoppableJobs            NullInt64 `info:"Unstoppable Jobs" json:"unstoppable_jobs"`
+	Listeners                  NullInt64 `info:"Listeners" json:"listeners"`
+	ActivePeers                NullInt64 `info:"ActivePeers" json:"activepeers"`
+	ConnectedPeers             NullInt64 `info:"ConnectedPeers" json:"connectedpeers"`
+	DroppedLogs                NullInt64 `info:"DroppedLogs" json:"droppedlogs"`
+	BusyPolling                NullInt64 `info:"BusyPolling" json:"busypolling"`
+	FailedResolutions          NullInt64 `info:"FailedResolutions" json:"failedresolutions"`
+	TotalBytesOut              NullInt64 `info:"TotalBytesOut" json:"totalbytesout"`
+	TotalSplicedBytesOut       NullInt64 `info:"TotalSplicedBytesOut" json:"totalsplicedbytesout"`
+	BytesOutRate               NullInt64 `info:"BytesOutRate" json:"bytesoutrate"`
+	DebugCommandsIssued        NullInt64 `info:"DebugCommandsIssued" json:"debugcommandsissued"`
+	CumRecvLogs                NullInt64 `info:"CumRecvLogs" json:"cumrecvlogs"`
+	BuildInfo                  string    `info:"Build info" json:"build_info,omitempty"`
+	MaxconnReached             NullInt64 `info:"MaxconnReached" json:"maxconnreached"`
+	BootTimeMs                 NullInt64 `info:"BootTime_ms" json:"boottime_ms"`
+}
+
+// infoFieldIndex maps "show info" name to Info field index
+var infoFieldIndex = func() map[string]int {
+	ret := make(map[string]int)
+	t := reflect.TypeOf(Info{})
+	for i := 0; i < t.NumField(); i++ {
+		ret[t.Field(i).Tag.Get("info")] = i
+	}
+
+	return ret
+}()
+
+// ParseInfo parses "show info" output into Info.
+// Unknown names are ignored.
+func ParseInfo(data io.Reader) (*Info, []byte, error) {
+	rawData, err := io.ReadAll(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read error: %w", err)
+	}
+
+	info := &Info{}
+	v := reflect.ValueOf(info).Elem()
+	found := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(rawData))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, nil, fmt.Errorf("info parse error: line %d: malformed line: %q", lineNo, line)
+		}
+
+		fi, ok := infoFieldIndex[name]
+		if !ok {
+			continue
+		}
+
+		err := setValue(v.Field(fi), "", strings.TrimSpace(value))
+		if err != nil {
+			return nil, nil, fmt.Errorf("info parse error: line %d: %s: %w", lineNo, name, err)
+		}
+		found++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("read error: %w", err)
+	}
+
+	if found == 0 {
+		return nil, nil, fmt.Errorf("info parse error: no known fields")
+	}
+
+	return info, rawData, nil
+}
+
+// GetInfo query HAProxy for process Info
+func GetInfo(d *Dialer) (*Info, []byte, error) {
+	data, err := d.Exec("show info")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ParseInfo(bytes.NewReader(data))
+}
+
+// UsagePercentage calculates percentage of cur to limit.
+// ok is false if the limit is not set (zero means unlimited).
+func UsagePercentage(cur, limit NullInt64) (pct float32, ok bool) {
+	if limit.Or(0) <= 0 {
+		return 0, false
+	}
+
+	return 100.0 * cur.Float32() / limit.Float32(), true
+}
@@ -0,0 +1,117 @@
+package haproxy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testingInfo = `Name: HAProxy
+Version: 2.4.22-0ubuntu0.22.04.3
+Release_date: 2023/08/14
+Nbthread: 4
+Nbproc: 1
+Process_num: 1
+Pid: 1234
+Uptime: 0d 3h07m45s
+Uptime_sec: 11265
+Memmax_MB: 0
+PoolAlloc_MB: 2
+PoolUsed_MB: 2
+PoolFailed: 0
+Ulimit-n: 200039
+Maxsock: 200039
+Maxconn: 100000
+Hard_maxconn: 100000
+CurrConns: 91000
+CumConns: 26482
+CumReq: 52914
+MaxSslConns: 0
+CurrSslConns: 4
+CumSslConns: 3193
+Maxpipes: 0
+PipesUsed: 0
+PipesFree: 0
+ConnRate: 3
+ConnRateLimit: 0
+MaxConnRate: 31
+SessRate: 3
+SessRateLimit: 0
+MaxSessRate: 31
+SslRate: 0
+SslRateLimit: 100
+MaxSslRate: 7
+SslFrontendKeyRate: 0
+SslFrontendMaxKeyRate: 7
+SslFrontendSessionReuse_pct: 0
+SslBackendKeyRate: 0
+SslBackendMaxKeyRate: 0
+SslCacheLookups: 0
+SslCacheMisses: 0
+CompressBpsIn: 0
+CompressBpsOut: 0
+CompressBpsRateLim: 0
+Tasks: 241
+Run_queue: 1
+Idle_pct: 8
+node: lb01
+Stopping: 0
+Jobs: 17
+Unstoppable Jobs: 0
+Listeners: 10
+ActivePeers: 0
+ConnectedPeers: 0
+DroppedLogs: 0
+BusyPolling: 0
+FailedResolutions: 0
+TotalBytesOut: 109432412
+TotalSplicedBytesOut: 0
+BytesOutRate: 1120
+DebugCommandsIssued: 0
+CumRecvLogs: 0
+Build info: 2.4.22-0ubuntu0.22.04.3
+Memmax_bytes: 0
+`
+
+func TestParseInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	info, _, err := ParseInfo(strings.NewReader(testingInfo))
+	assert.NoError(err)
+	assert.Equal("2.4.22-0ubuntu0.22.04.3", info.Version)
+	assert.Equal("lb01", info.Node)
+	assert.Equal(NewInt64(100000), info.Maxconn)
+	assert.Equal(NewInt64(91000), info.CurrConns)
+	assert.Equal(NewInt64(200039), info.UlimitN)
+	assert.Equal(NewInt64(0), info.UnstoppableJobs)
+	assert.Equal(NewInt64(8), info.IdlePct)
+	assert.Equal(NullInt64{}, info.MaxconnReached)
+
+	pct, ok := UsagePercentage(info.CurrConns, info.Maxconn)
+	assert.True(ok)
+	assert.InDelta(91.0, pct, 0.01)
+
+	_, ok = UsagePercentage(info.CurrSslConns, info.MaxSslConns)
+	assert.False(ok)
+
+	_, _, err = ParseInfo(strings.NewReader("Unknown command, but maybe one of the following ones is a better match:\n  help : this message\n"))
+	assert.Error(err)
+
+	_, _, err = ParseInfo(strings.NewReader("Maxconn: lots\n"))
+	assert.Error(err)
+}
+
+func TestGetInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	socketPath := filepath.Join(t.TempDir(), "haproxy.sock")
+	serveRuntimeAPI(t, "unix", socketPath, map[string]string{
+		"show info": testingInfo,
+	})
+
+	info, _, err := GetInfo(&Dialer{Network: "unix", Address: socketPath})
+	assert.NoError(err)
+	assert.Equal(NewInt64(1234), info.Pid)
+}
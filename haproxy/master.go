@@ -0,0 +1,208 @@
+package haproxy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Process is a process of the master-worker model reported by master CLI "show proc"
+type Process struct {
+	PID     int
+	Type    string
+	Reloads string
+	Uptime  string
+	Version string
+	// Old is set for workers of the previous generations which still serve sessions after reload
+	Old bool
+}
+
+// IsWorker checks that the process serves traffic
+func (p Process) IsWorker() bool {
+	return p.Type == "worker"
+}
+
+// ParseProc parses master CLI "show proc" output:
+//
+//	#<PID>          <type>          <reloads>       <uptime>        <version>
+//	1160            master          1 [failed: 0]   0d00h02m53s     2.4.0
+//	# workers
+//	1192            worker          0               0d00h00m12s     2.4.0
+//	# old workers
+//	1173            worker          1               0d00h01m03s     2.4.0
+//	# programs
+func ParseProc(data io.Reader) ([]Process, error) {
+	ret := make([]Process, 0)
+	old := false
+
+	scanner := bufio.NewScanner(data)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			section := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+			old = section == "old workers"
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("proc parse error: line %d: malformed line: %q", lineNo, line)
+		}
+
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("proc parse error: line %d: %w", lineNo, err)
+		}
+
+		p := Process{
+			PID:  pid,
+			Type: fields[1],
+			Old:  old,
+		}
+
+		// reloads may be followed by "[failed: N]", so uptime and version are taken from the end
+		if n := len(fields); n >= 5 {
+			p.Reloads = strings.Join(fields[2:n-2], " ")
+			p.Uptime = fields[n-2]
+			p.Version = fields[n-1]
+		}
+
+		ret = append(ret, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read error: %w", err)
+	}
+
+	return ret, nil
+}
+
+// GetProcs query master CLI for processes
+func GetProcs(d *Dialer) ([]Process, error) {
+	data, err := d.Exec("show proc")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseProc(bytes.NewReader(data))
+}
+
+// workerCommand makes master CLI command routed to the worker by PID
+func workerCommand(pid int, cmd string) string {
+	return fmt.Sprintf("@!%d %s", pid, cmd)
+}
+
+// Executor sends one command to the runtime API, like Dialer does
+type Executor interface {
+	Exec(cmd string) ([]byte, error)
+}
+
+// Worker routes commands to the worker process through the master CLI
+type Worker struct {
+	Master *Dialer
+	PID    int
+}
+
+// ErrWorkerGone is returned when the master CLI does not know the worker PID,
+// e.g. the old worker exited after reload
+var ErrWorkerGone = errors.New("worker is gone")
+
+// masterNoTarget is the master CLI reply to commands routed to unknown PID:
+// "Can't find the target PID matching the prefix '@!1173'"
+const masterNoTarget = "Can't find the target PID"
+
+// Exec sends one command to the worker, master CLI error reply is returned as error
+func (w *Worker) Exec(cmd string) ([]byte, error) {
+	data, err := w.Master.Exec(workerCommand(w.PID, cmd))
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(masterNoTarget)) {
+		return nil, ErrWorkerGone
+	}
+
+	return data, nil
+}
+
+// CurrentWorker finds the worker of the current generation through the master CLI
+func CurrentWorker(d *Dialer) (*Worker, error) {
+	procs, err := GetProcs(d)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range procs {
+		if p.IsWorker() && !p.Old {
+			return &Worker{Master: d, PID: p.PID}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no workers found")
+}
+
+// GetMasterStats query all workers, including old ones, through the master CLI
+// and aggregates their Stats. Old workers which exited meanwhile are skipped.
+// Raw data of each worker is prefixed by "# @!<pid>" line.
+func GetMasterStats(d *Dialer, format StatFormat) (Stats, []byte, error) {
+	procs, err := GetProcs(d)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rawData bytes.Buffer
+	lines := make([]StatLine, 0)
+	for _, p := range procs {
+		if !p.IsWorker() {
+			continue
+		}
+
+		w := &Worker{Master: d, PID: p.PID}
+		data, err := w.Exec(format.Command())
+		if errors.Is(err, ErrWorkerGone) && p.Old {
+			continue
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("worker %d: %w", p.PID, err)
+		}
+
+		stats, _, err := ParseStats(format, bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("worker %d: %w", p.PID, err)
+		}
+
+		fmt.Fprintf(&rawData, "# @!%d\n", p.PID)
+		rawData.Write(data)
+
+		for _, svc := range stats {
+			lines = append(lines, svc.Lines()...)
+		}
+	}
+
+	if rawData.Len() == 0 {
+		return nil, nil, fmt.Errorf("no workers found")
+	}
+
+	return statsFromLines(lines), rawData.Bytes(), nil
+}
+
+// GetMasterInfo query process Info of the current worker through the master CLI
+func GetMasterInfo(d *Dialer) (*Info, []byte, error) {
+	w, err := CurrentWorker(d)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := w.Exec("show info")
+	if err != nil {
+		return nil, nil, fmt.Errorf("worker %d: %w", w.PID, err)
+	}
+
+	return ParseInfo(bytes.NewReader(data))
+}
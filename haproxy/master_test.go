@@ -0,0 +1,108 @@
+package haproxy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testingProc = `#<PID>          <type>          <reloads>       <uptime>        <version>
+1160            master          1 [failed: 0]   0d00h02m53s     2.4.0
+# workers
+1192            worker          0               0d00h00m12s     2.4.0
+# old workers
+1173            worker          1               0d00h01m03s     2.4.0
+# programs
+
+`
+
+func TestParseProc(t *testing.T) {
+	assert := assert.New(t)
+
+	procs, err := ParseProc(strings.NewReader(testingProc))
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal([]Process{
+		{PID: 1160, Type: "master", Reloads: "1 [failed: 0]", Uptime: "0d00h02m53s", Version: "2.4.0"},
+		{PID: 1192, Type: "worker", Reloads: "0", Uptime: "0d00h00m12s", Version: "2.4.0"},
+		{PID: 1173, Type: "worker", Reloads: "1", Uptime: "0d00h01m03s", Version: "2.4.0", Old: true},
+	}, procs)
+
+	_, err = ParseProc(strings.NewReader("abc worker\n"))
+	assert.Error(err)
+}
+
+func TestGetMasterStats(t *testing.T) {
+	assert := assert.New(t)
+
+	// old worker still has 2 sessions and a DOWN server
+	oldCSV := strings.Replace(testingCSV,
+		"ipmi_exporter,ctrl01,0,0,0,2,,1683,445808,2145275,,0,,0,0,0,0,UP,",
+		"ipmi_exporter,ctrl01,0,0,2,2,,1683,445808,2145275,,0,,0,0,0,0,DOWN,", 1)
+
+	socketPath := filepath.Join(t.TempDir(), "master.sock")
+	serveRuntimeAPI(t, "unix", socketPath, map[string]string{
+		"show proc":        testingProc,
+		"@!1192 show stat": testingCSV,
+		"@!1173 show stat": oldCSV,
+		"@!1192 show info": testingInfo,
+	})
+
+	d := &Dialer{Network: "unix", Address: socketPath}
+	stats, rawData, err := GetMasterStats(d, StatFormatCSV)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(stats, 4)
+	assert.Contains(string(rawData), "# @!1173\n")
+
+	srv := stats["ipmi_exporter"]["ctrl01"]
+	assert.Equal("DOWN", srv.Status)
+	assert.Equal(int64(2), srv.Scur.Int64)
+	assert.Equal(int64(2*1683), srv.Stot.Int64)
+	assert.Equal(int64(1752), srv.Rtime.Int64)
+	assert.Equal(int64(1), srv.Weight.Int64)
+
+	info, _, err := GetMasterInfo(d)
+	if assert.NoError(err) {
+		assert.True(info.Maxconn.Valid)
+	}
+}
+
+func TestGetMasterStatsWorkerGone(t *testing.T) {
+	assert := assert.New(t)
+
+	// the old worker exited between show proc and show stat
+	socketPath := filepath.Join(t.TempDir(), "master.sock")
+	serveRuntimeAPI(t, "unix", socketPath, map[string]string{
+		"show proc":        testingProc,
+		"@!1192 show stat": testingCSV,
+		"@!1173 show stat": "Can't find the target PID matching the prefix '@!1173'\n",
+	})
+
+	d := &Dialer{Network: "unix", Address: socketPath}
+	stats, rawData, err := GetMasterStats(d, StatFormatCSV)
+	if assert.NoError(err) {
+		assert.Len(stats, 4)
+		assert.NotContains(string(rawData), "# @!1173\n")
+	}
+
+	// reply of the current worker is an error for any stat format
+	socketPath = filepath.Join(t.TempDir(), "master.sock")
+	serveRuntimeAPI(t, "unix", socketPath, map[string]string{
+		"show proc":              testingProc,
+		"@!1192 show stat":       "Can't find the target PID matching the prefix '@!1192'\n",
+		"@!1192 show stat typed": "Can't find the target PID matching the prefix '@!1192'\n",
+		"@!1173 show stat":       testingCSV,
+	})
+
+	d = &Dialer{Network: "unix", Address: socketPath}
+	for _, format := range []StatFormat{StatFormatCSV, StatFormatTyped} {
+		_, _, err = GetMasterStats(d, format)
+		assert.ErrorIs(err, ErrWorkerGone, format)
+	}
+}
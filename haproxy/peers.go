@@ -0,0 +1,222 @@
+package haproxy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Peers section flags of initial resynchronization
+const (
+	// PeersResyncLocal is set when learning from the local (old) process finished or is not needed
+	PeersResyncLocal uint64 = 0x01
+	// PeersResyncRemote is set when learning from a remote peer finished or is not needed
+	PeersResyncRemote uint64 = 0x02
+)
+
+// PeerStatusEstablished is the last status of a connected peer
+const PeerStatusEstablished = "ESTA"
+
+// PeersSection is a peers section reported by "show peers"
+type PeersSection struct {
+	ID            string `json:"id"`
+	Flags         uint64 `json:"flags"`
+	ResyncTimeout string `json:"resync_timeout,omitempty"`
+	Peers         []Peer `json:"peers"`
+}
+
+// ResyncDone reports if initial resynchronization of stick tables finished
+func (s PeersSection) ResyncDone() bool {
+	return s.Flags&(PeersResyncLocal|PeersResyncRemote) == PeersResyncLocal|PeersResyncRemote
+}
+
+// Peer is a peer of the section.
+// Status is last_status of HAProxy 2.4+ or status of older versions.
+type Peer struct {
+	Name          string      `json:"name"`
+	Local         bool        `json:"local"`
+	Addr          string      `json:"addr,omitempty"`
+	Status        string      `json:"status,omitempty"`
+	AppState      string      `json:"app_state,omitempty"`
+	LearnState    string      `json:"learn_state,omitempty"`
+	Reconnect     string      `json:"reconnect,omitempty"`
+	LastHandshake string      `json:"last_handshake,omitempty"`
+	Tables        []PeerTable `json:"tables,omitempty"`
+}
+
+// PeerTable is a stick table shared with the peer
+type PeerTable struct {
+	Name       string `json:"name"`
+	LastAcked  int64  `json:"last_acked"`
+	LastPushed int64  `json:"last_pushed"`
+	Update     int64  `json:"update"`
+}
+
+var (
+	peersSectionRe = regexp.MustCompile(`^0x[0-9a-fA-F]+: `)
+	peerRe         = regexp.MustCompile(`^\s+0x[0-9a-fA-F]+: id=`)
+	peerTableRe    = regexp.MustCompile(`^\s+0x[0-9a-fA-F]+\s`)
+)
+
+// keyValues collects name=value fields of the line, the first value of a name wins
+func keyValues(line string, values map[string]string) {
+	for _, field := range strings.Fields(line) {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+
+		if _, found := values[name]; !found {
+			values[name] = value
+		}
+	}
+}
+
+// ParsePeers parses "show peers" output:
+//
+//	0x55b9b8d38c00: [14/Jul/2023:10:00:00] id=mypeers disabled=0 flags=0x3 resync_timeout=<PAST> task_calls=96
+//	  0x55b9b8d3a1e0: id=hap2(remote,active) addr=10.0.0.2:10000 app_state=RUNNING learn_state=NOTASSIGNED last_status=ESTA last_hdshk=1m2s
+//	        reconnect=4s heartbeat=2s confirm=0 tx_hbt=30 rx_hbt=31 no_hbt=0 new_conn=2 proto_err=0 coll=0
+//	        shared tables:
+//	          0x55b9b8d3c2a0 local_id=1 remote_id=1 flags=0x0 remote_data=0x0
+//	                 last_acked=0 last_pushed=3 last_get=0 teaching_origin=0 update=3
+//	                 table:0x55b9b8d3b1c0 id=bk_stick update=3 localupdate=3 commitupdate=3 refcnt=1
+//	  0x55b9b8d3a5f0: id=hap1(local,inactive) addr=10.0.0.1:10000 app_state=RUNNING last_status=NONE
+func ParsePeers(data io.Reader) ([]PeersSection, error) {
+	ret := make([]PeersSection, 0)
+
+	// kind and values of the object being parsed: section, peer or table
+	var kind string
+	var values map[string]string
+	flush := func() error {
+		if values == nil || len(ret) == 0 {
+			return nil
+		}
+
+		section := &ret[len(ret)-1]
+		switch kind {
+		case "section":
+			section.ID = values["id"]
+			section.ResyncTimeout = values["resync_timeout"]
+			if flags, ok := values["flags"]; ok {
+				v, err := strconv.ParseUint(strings.TrimPrefix(flags, "0x"), 16, 64)
+				if err != nil {
+					return fmt.Errorf("flags: %w", err)
+				}
+				section.Flags = v
+			}
+
+		case "peer":
+			name, role, _ := strings.Cut(strings.TrimSuffix(values["id"], ")"), "(")
+			status := values["last_status"]
+			if status == "" {
+				status = values["status"]
+			}
+			section.Peers = append(section.Peers, Peer{
+				Name:          name,
+				Local:         strings.HasPrefix(role, "local"),
+				Addr:          values["addr"],
+				Status:        status,
+				AppState:      values["app_state"],
+				LearnState:    values["learn_state"],
+				Reconnect:     values["reconnect"],
+				LastHandshake: values["last_hdshk"],
+			})
+
+		case "table":
+			if len(section.Peers) == 0 {
+				return fmt.Errorf("shared table without peer")
+			}
+			t := PeerTable{Name: values["id"]}
+			for name, v := range map[string]*int64{"last_acked": &t.LastAcked, "last_pushed": &t.LastPushed, "update": &t.Update} {
+				if s, ok := values[name]; ok {
+					n, err := strconv.ParseInt(s, 10, 64)
+					if err != nil {
+						return fmt.Errorf("%s: %w", name, err)
+					}
+					*v = n
+				}
+			}
+			peer := &section.Peers[len(section.Peers)-1]
+			peer.Tables = append(peer.Tables, t)
+		}
+
+		values = nil
+		return nil
+	}
+
+	begin := func(k string) {
+		kind = k
+		values = make(map[string]string)
+	}
+
+	sharedTables := false
+	scanner := bufio.NewScanner(data)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var err error
+		switch {
+		case peersSectionRe.MatchString(line):
+			err = flush()
+			ret = append(ret, PeersSection{Peers: make([]Peer, 0)})
+			begin("section")
+			sharedTables = false
+
+		case peerRe.MatchString(line):
+			err = flush()
+			begin("peer")
+			sharedTables = false
+
+		case strings.TrimSpace(line) == "shared tables:":
+			err = flush()
+			sharedTables = true
+			continue
+
+		case sharedTables && peerTableRe.MatchString(line):
+			err = flush()
+			begin("table")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("peers parse error: line %d: %w", lineNo, err)
+		}
+
+		if len(ret) == 0 {
+			return nil, fmt.Errorf("peers parse error: line %d: malformed line: %q", lineNo, line)
+		}
+
+		// id of the shared table is in "table:0x... id=<name>" line
+		keyValues(line, values)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read error: %w", err)
+	}
+
+	if err := flush(); err != nil {
+		return nil, fmt.Errorf("peers parse error: %w", err)
+	}
+
+	return ret, nil
+}
+
+// GetPeers query HAProxy for peers sections
+func GetPeers(e Executor) ([]PeersSection, []byte, error) {
+	data, err := e.Exec("show peers")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	peers, err := ParsePeers(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return peers, data, nil
+}
@@ -0,0 +1,79 @@
+package haproxy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testingPeers = `0x55b9b8d38c00: [14/Jul/2023:10:00:00] id=mypeers disabled=0 flags=0x3 resync_timeout=<PAST> task_calls=96
+  0x55b9b8d3a1e0: id=hap2(remote,active) addr=10.0.0.2:10000 app_state=RUNNING learn_state=NOTASSIGNED last_status=ESTA last_hdshk=1m2s
+        reconnect=4s heartbeat=2s confirm=0 tx_hbt=30 rx_hbt=31 no_hbt=0 new_conn=2 proto_err=0 coll=0
+        flags=0x0 appctx:0x55b9b8d3e000 st0=7 st1=0 task_calls=13 state=EST
+        xprt=RAW src=10.0.0.1:36518 addr=10.0.0.2:10000
+        remote_table:0x55b9b8d3b1c0 id=bk_stick local_id=1 remote_id=1
+        last_local_table:0x55b9b8d3b1c0 id=bk_stick local_id=1 remote_id=1
+        shared tables:
+          0x55b9b8d3c2a0 local_id=1 remote_id=1 flags=0x0 remote_data=0x0
+                 last_acked=2 last_pushed=3 last_get=0 teaching_origin=0 update=3
+                 table:0x55b9b8d3b1c0 id=bk_stick update=5 localupdate=3 commitupdate=3 refcnt=1
+                 Dictionary cache not dumped (use "show peers dict")
+  0x55b9b8d3a5f0: id=hap1(local,inactive) addr=10.0.0.1:10000 app_state=RUNNING learn_state=NOTASSIGNED last_status=NONE last_hdshk=<NEVER>
+        reconnect=<NEVER> heartbeat=<NEVER> confirm=0 tx_hbt=0 rx_hbt=0 no_hbt=0 new_conn=0 proto_err=0 coll=0
+        flags=0x0
+        shared tables:
+          0x55b9b8d3c3b0 local_id=1 remote_id=0 flags=0x0 remote_data=0x0
+                 last_acked=0 last_pushed=0 last_get=0 teaching_origin=0 update=0
+                 table:0x55b9b8d3b1c0 id=bk_stick update=3 localupdate=3 commitupdate=3 refcnt=1
+0x55deb0224320: [15/Apr/2019:11:28:01] id=legacy state=0 flags=0x1 resync_timeout=2s task_calls=27
+  0x55deb022b540: id=some_peer(remote) addr=127.0.0.10:10000 status=CONN reconnect=3s confirm=0
+        flags=0x0
+
+`
+
+func TestParsePeers(t *testing.T) {
+	assert := assert.New(t)
+
+	sections, err := ParsePeers(strings.NewReader(testingPeers))
+	if !assert.NoError(err) || !assert.Len(sections, 2) {
+		return
+	}
+
+	s := sections[0]
+	assert.Equal("mypeers", s.ID)
+	assert.Equal("<PAST>", s.ResyncTimeout)
+	assert.True(s.ResyncDone())
+	if assert.Len(s.Peers, 2) {
+		assert.Equal(Peer{
+			Name:          "hap2",
+			Addr:          "10.0.0.2:10000",
+			Status:        PeerStatusEstablished,
+			AppState:      "RUNNING",
+			LearnState:    "NOTASSIGNED",
+			Reconnect:     "4s",
+			LastHandshake: "1m2s",
+			Tables:        []PeerTable{{Name: "bk_stick", LastAcked: 2, LastPushed: 3, Update: 3}},
+		}, s.Peers[0])
+		assert.Equal("hap1", s.Peers[1].Name)
+		assert.True(s.Peers[1].Local)
+		assert.Len(s.Peers[1].Tables, 1)
+	}
+
+	s = sections[1]
+	assert.Equal("legacy", s.ID)
+	assert.False(s.ResyncDone())
+	if assert.Len(s.Peers, 1) {
+		assert.Equal("some_peer", s.Peers[0].Name)
+		assert.False(s.Peers[0].Local)
+		assert.Equal("CONN", s.Peers[0].Status)
+		assert.Equal("3s", s.Peers[0].Reconnect)
+	}
+
+	sections, err = ParsePeers(strings.NewReader(""))
+	assert.NoError(err)
+	assert.Empty(sections)
+
+	_, err = ParsePeers(strings.NewReader("Unknown command\n"))
+	assert.Error(err)
+}
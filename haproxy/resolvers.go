@@ -0,0 +1,110 @@
+package haproxy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ResolverErrorCounters are nameserver counters of failed queries
+var ResolverErrorCounters = []string{"snd_error", "cname_error", "any_err", "nx", "timeout", "refused", "other", "invalid", "too_big", "truncated"}
+
+// ResolversSection is a resolvers section reported by "show resolvers"
+type ResolversSection struct {
+	Name        string       `json:"name"`
+	Nameservers []Nameserver `json:"nameservers"`
+}
+
+// Nameserver is a nameserver of the resolvers section with its counters:
+// sent, snd_error, valid, update, cname, cname_error, any_err, nx, timeout,
+// refused, other, invalid, too_big, truncated and outdated.
+type Nameserver struct {
+	Name     string           `json:"name"`
+	Counters map[string]int64 `json:"counters"`
+}
+
+// Errors returns the number of failed queries
+func (ns Nameserver) Errors() int64 {
+	var ret int64
+	for _, name := range ResolverErrorCounters {
+		ret += ns.Counters[name]
+	}
+
+	return ret
+}
+
+// ParseResolvers parses "show resolvers" output:
+//
+//	Resolvers section mydns
+//	 nameserver dns1:
+//	  sent:        8
+//	  snd_error:   0
+//	  valid:       4
+//	  ...
+//	  outdated:    0
+func ParseResolvers(data io.Reader) ([]ResolversSection, error) {
+	ret := make([]ResolversSection, 0)
+
+	scanner := bufio.NewScanner(data)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if name, ok := strings.CutPrefix(line, "Resolvers section "); ok {
+			ret = append(ret, ResolversSection{Name: strings.TrimSpace(name), Nameservers: make([]Nameserver, 0)})
+			continue
+		}
+		if len(ret) == 0 {
+			return nil, fmt.Errorf("resolvers parse error: line %d: malformed line: %q", lineNo, line)
+		}
+		section := &ret[len(ret)-1]
+
+		if name, ok := strings.CutPrefix(line, "nameserver "); ok {
+			section.Nameservers = append(section.Nameservers, Nameserver{
+				Name:     strings.TrimSuffix(strings.TrimSpace(name), ":"),
+				Counters: make(map[string]int64),
+			})
+			continue
+		}
+		if len(section.Nameservers) == 0 {
+			return nil, fmt.Errorf("resolvers parse error: line %d: malformed line: %q", lineNo, line)
+		}
+		ns := &section.Nameservers[len(section.Nameservers)-1]
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("resolvers parse error: line %d: malformed line: %q", lineNo, line)
+		}
+
+		v, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("resolvers parse error: line %d: %s: %w", lineNo, name, err)
+		}
+		ns.Counters[strings.TrimSpace(name)] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read error: %w", err)
+	}
+
+	return ret, nil
+}
+
+// GetResolvers query HAProxy for resolvers sections
+func GetResolvers(e Executor) ([]ResolversSection, []byte, error) {
+	data, err := e.Exec("show resolvers")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolvers, err := ParseResolvers(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resolvers, data, nil
+}
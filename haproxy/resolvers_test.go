@@ -0,0 +1,58 @@
+package haproxy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testingResolvers = `Resolvers section mydns
+ nameserver dns1:
+  sent:        8
+  snd_error:   0
+  valid:       4
+  update:      0
+  cname:       0
+  cname_error: 0
+  any_err:     0
+  nx:          1
+  timeout:     2
+  refused:     0
+  other:       0
+  invalid:     0
+  too_big:     0
+  truncated:   0
+  outdated:    1
+ nameserver dns2:
+  sent:        8
+  valid:       8
+
+`
+
+func TestParseResolvers(t *testing.T) {
+	assert := assert.New(t)
+
+	sections, err := ParseResolvers(strings.NewReader(testingResolvers))
+	if !assert.NoError(err) || !assert.Len(sections, 1) {
+		return
+	}
+
+	s := sections[0]
+	assert.Equal("mydns", s.Name)
+	if assert.Len(s.Nameservers, 2) {
+		ns := s.Nameservers[0]
+		assert.Equal("dns1", ns.Name)
+		assert.Equal(int64(8), ns.Counters["sent"])
+		assert.Equal(int64(4), ns.Counters["valid"])
+		assert.Equal(int64(1), ns.Counters["outdated"])
+		assert.Equal(int64(3), ns.Errors())
+		assert.Equal(int64(0), s.Nameservers[1].Errors())
+	}
+
+	_, err = ParseResolvers(strings.NewReader(" nameserver dns1:\n"))
+	assert.Error(err)
+
+	_, err = ParseResolvers(strings.NewReader("Resolvers section mydns\n nameserver dns1:\n  sent: many\n"))
+	assert.Error(err)
+}
@@ -0,0 +1,203 @@
+package haproxy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SSLCert is a certificate loaded by HAProxy, reported by "show ssl cert <name>"
+type SSLCert struct {
+	Filename  string         `json:"filename"`
+	Status    string         `json:"status,omitempty"`
+	Serial    string         `json:"serial,omitempty"`
+	NotBefore time.Time      `json:"not_before"`
+	NotAfter  time.Time      `json:"not_after"`
+	Subject   string         `json:"subject,omitempty"`
+	Issuer    string         `json:"issuer,omitempty"`
+	SANs      []string       `json:"sans,omitempty"`
+	Algorithm string         `json:"algorithm,omitempty"`
+	Chain     []SSLChainCert `json:"chain,omitempty"`
+	// Uncommitted is set when "set ssl cert" transaction of the file is not committed
+	Uncommitted bool `json:"uncommitted,omitempty"`
+}
+
+// SSLChainCert is an intermediate certificate of the chain
+type SSLChainCert struct {
+	Subject string `json:"subject"`
+	Issuer  string `json:"issuer,omitempty"`
+}
+
+// sslTimeLayout is OpenSSL ASN1_TIME_print format, day is space padded
+const sslTimeLayout = "Jan 2 15:04:05 2006 MST"
+
+// ParseSSLCertList parses "show ssl cert" output.
+// Files with uncommitted transaction are listed in "# transaction" section with "*" prefix:
+//
+//	# transaction
+//	*/etc/haproxy/ssl/site.pem
+//	# filename
+//	/etc/haproxy/ssl/site.pem
+//	/etc/haproxy/ssl/api.pem
+func ParseSSLCertList(data io.Reader) (filenames, transactions []string, err error) {
+	filenames = make([]string, 0)
+	transactions = make([]string, 0)
+
+	scanner := bufio.NewScanner(data)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if name, ok := strings.CutPrefix(line, "*"); ok {
+			transactions = append(transactions, name)
+			continue
+		}
+
+		filenames = append(filenames, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("read error: %w", err)
+	}
+
+	return filenames, transactions, nil
+}
+
+// ParseSSLCert parses "show ssl cert <name>" output:
+//
+//	Filename: /etc/haproxy/ssl/site.pem
+//	Status: Used
+//	Serial: 0D933C1B1089BF660AE5253A245BB388
+//	notBefore: Sep  9 12:00:00 2020 GMT
+//	notAfter: Sep 14 12:00:00 2021 GMT
+//	Subject Alternative Name: DNS:example.com, DNS:www.example.com
+//	Algorithm: RSA2048
+//	Subject: /CN=example.com
+//	Issuer: /C=US/O=Let's Encrypt/CN=R3
+//	Chain Subject: /C=US/O=Let's Encrypt/CN=R3
+//	Chain Issuer: /C=US/O=Internet Security Research Group/CN=ISRG Root X1
+//
+// Unknown names are ignored.
+func ParseSSLCert(data io.Reader) (*SSLCert, error) {
+	cert := &SSLCert{}
+
+	scanner := bufio.NewScanner(data)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("ssl cert parse error: line %d: malformed line: %q", lineNo, line)
+		}
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch name {
+		case "Filename":
+			cert.Filename = strings.TrimPrefix(value, "*")
+		case "Status":
+			cert.Status = value
+		case "Serial":
+			cert.Serial = value
+		case "notBefore":
+			cert.NotBefore, err = parseSSLTime(value)
+		case "notAfter":
+			cert.NotAfter, err = parseSSLTime(value)
+		case "Subject Alternative Name":
+			for _, san := range strings.Split(value, ",") {
+				if san = strings.TrimSpace(san); san != "" {
+					cert.SANs = append(cert.SANs, san)
+				}
+			}
+		case "Algorithm":
+			cert.Algorithm = value
+		case "Subject":
+			cert.Subject = value
+		case "Issuer":
+			cert.Issuer = value
+		case "Chain Subject":
+			cert.Chain = append(cert.Chain, SSLChainCert{Subject: value})
+		case "Chain Issuer":
+			if len(cert.Chain) == 0 {
+				return nil, fmt.Errorf("ssl cert parse error: line %d: Chain Issuer without Chain Subject", lineNo)
+			}
+			cert.Chain[len(cert.Chain)-1].Issuer = value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ssl cert parse error: line %d: %s: %w", lineNo, name, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read error: %w", err)
+	}
+
+	if cert.Filename == "" {
+		return nil, fmt.Errorf("ssl cert parse error: no Filename")
+	}
+
+	return cert, nil
+}
+
+func parseSSLTime(value string) (time.Time, error) {
+	return time.Parse(sslTimeLayout, strings.Join(strings.Fields(value), " "))
+}
+
+// GetSSLCerts query HAProxy for details of all loaded certificates.
+// Files with uncommitted "set ssl cert" transaction are marked.
+// Raw data of each certificate is prefixed by "# show ssl cert <name>" line.
+func GetSSLCerts(e Executor) ([]SSLCert, []byte, error) {
+	data, err := e.Exec("show ssl cert")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filenames, transactions, err := ParseSSLCertList(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uncommitted := make(map[string]bool, len(transactions))
+	for _, name := range transactions {
+		uncommitted[name] = true
+	}
+
+	var rawData bytes.Buffer
+	rawData.Write(data)
+
+	ret := make([]SSLCert, 0, len(filenames))
+	for _, name := range filenames {
+		cmd := "show ssl cert " + name
+		data, err := e.Exec(cmd)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		fmt.Fprintf(&rawData, "# %s\n", cmd)
+		rawData.Write(data)
+
+		cert, err := ParseSSLCert(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		cert.Uncommitted = uncommitted[name]
+		delete(uncommitted, name)
+		ret = append(ret, *cert)
+	}
+
+	// transactions of files which are not loaded yet
+	for _, name := range transactions {
+		if uncommitted[name] {
+			ret = append(ret, SSLCert{Filename: name, Uncommitted: true})
+		}
+	}
+
+	return ret, rawData.Bytes(), nil
+}
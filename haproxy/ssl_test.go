@@ -0,0 +1,107 @@
+package haproxy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testingSSLCertList = `# transaction
+*/etc/haproxy/ssl/site.pem
+# filename
+/etc/haproxy/ssl/site.pem
+/etc/haproxy/ssl/api.pem
+
+`
+
+const testingSSLCert = `Filename: /etc/haproxy/ssl/site.pem
+Status: Used
+Serial: 0D933C1B1089BF660AE5253A245BB388
+notBefore: Sep  9 12:00:00 2020 GMT
+notAfter: Sep 14 12:00:00 2021 GMT
+Subject Alternative Name: DNS:example.com, DNS:www.example.com
+Algorithm: RSA2048
+SHA1 FingerPrint: 0BE3F1D2B7D1B7B87D9A5D3F0BFD81B07A3D7E2F
+Subject: /CN=example.com
+Issuer: /C=US/O=Let's Encrypt/CN=R3
+Chain Subject: /C=US/O=Let's Encrypt/CN=R3
+Chain Issuer: /C=US/O=Internet Security Research Group/CN=ISRG Root X1
+
+`
+
+func TestParseSSLCert(t *testing.T) {
+	assert := assert.New(t)
+
+	filenames, transactions, err := ParseSSLCertList(strings.NewReader(testingSSLCertList))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal([]string{"/etc/haproxy/ssl/site.pem", "/etc/haproxy/ssl/api.pem"}, filenames)
+	assert.Equal([]string{"/etc/haproxy/ssl/site.pem"}, transactions)
+
+	cert, err := ParseSSLCert(strings.NewReader(testingSSLCert))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("/etc/haproxy/ssl/site.pem", cert.Filename)
+	assert.Equal("Used", cert.Status)
+	assert.Equal(time.Date(2020, time.September, 9, 12, 0, 0, 0, time.UTC), cert.NotBefore.UTC())
+	assert.Equal(time.Date(2021, time.September, 14, 12, 0, 0, 0, time.UTC), cert.NotAfter.UTC())
+	assert.Equal([]string{"DNS:example.com", "DNS:www.example.com"}, cert.SANs)
+	assert.Equal("/CN=example.com", cert.Subject)
+	assert.Equal([]SSLChainCert{{
+		Subject: "/C=US/O=Let's Encrypt/CN=R3",
+		Issuer:  "/C=US/O=Internet Security Research Group/CN=ISRG Root X1",
+	}}, cert.Chain)
+
+	_, err = ParseSSLCert(strings.NewReader("notAfter: yesterday\n"))
+	assert.Error(err)
+
+	_, err = ParseSSLCert(strings.NewReader("Status: Unused\n"))
+	assert.Error(err)
+}
+
+func TestGetSSLCerts(t *testing.T) {
+	assert := assert.New(t)
+
+	socketPath := filepath.Join(t.TempDir(), "haproxy.sock")
+	serveRuntimeAPI(t, "unix", socketPath, map[string]string{
+		"show ssl cert": testingSSLCertList,
+		"show ssl cert /etc/haproxy/ssl/site.pem": testingSSLCert,
+		"show ssl cert /etc/haproxy/ssl/api.pem":  strings.ReplaceAll(testingSSLCert, "site.pem", "api.pem"),
+	})
+
+	certs, rawData, err := GetSSLCerts(&Dialer{Network: "unix", Address: socketPath})
+	if !assert.NoError(err) || !assert.Len(certs, 2) {
+		return
+	}
+	assert.True(certs[0].Uncommitted)
+	assert.Equal("/etc/haproxy/ssl/api.pem", certs[1].Filename)
+	assert.False(certs[1].Uncommitted)
+	assert.Contains(string(rawData), "# show ssl cert /etc/haproxy/ssl/api.pem\n")
+}
+
+func TestGetSSLCertsWorker(t *testing.T) {
+	assert := assert.New(t)
+
+	socketPath := filepath.Join(t.TempDir(), "master.sock")
+	serveRuntimeAPI(t, "unix", socketPath, map[string]string{
+		"show proc":            testingProc,
+		"@!1192 show ssl cert": "# filename\n/etc/haproxy/ssl/site.pem\n",
+		"@!1192 show ssl cert /etc/haproxy/ssl/site.pem": testingSSLCert,
+	})
+
+	w, err := CurrentWorker(&Dialer{Network: "unix", Address: socketPath})
+	if !assert.NoError(err) {
+		return
+	}
+
+	certs, _, err := GetSSLCerts(w)
+	if assert.NoError(err) && assert.Len(certs, 1) {
+		assert.Equal("/etc/haproxy/ssl/site.pem", certs[0].Filename)
+		assert.False(certs[0].Uncommitted)
+	}
+}
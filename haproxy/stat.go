@@ -1,10 +1,13 @@
 package haproxy
 
 import (
+	"bytes"
 	"fmt"
 	"io"
-	"net"
-	"time"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/gocarina/gocsv"
 )
@@ -16,6 +19,14 @@ const (
 	Backend string = "BACKEND"
 )
 
+// Values of the type column
+const (
+	TypeFrontend int64 = iota
+	TypeBackend
+	TypeServer
+	TypeListener
+)
+
 // StatLine represents one line from haproxy stat report
 type StatLine struct {
 	// [[[cog:
@@ -28,125 +39,125 @@ type StatLine struct {
 	//     struct_name = field[0]
 	//     csv_name = field[1]
 	//     json_name = field[1].replace('# ', '')
-	//     type_ = 'string'
-	//     #if csv_name.endswith(('name', 'desc')) or csv_name in ['status', 'mode', 'check_status']:
-	//     #    type_ = 'string'
-	//     if csv_name in ['scur', 'slim']:	# NOTE(vermakov): we use only a few fields in check, leave others as string
-	//         type_ = 'int'
-	//     cog.outl(f"""{struct_name:28s} {type_:6s} `csv:"{csv_name}" json:"{json_name},omitempty"`""")
+	//     type_ = 'NullInt64'
+	//     if csv_name.endswith(('name', 'desc')) or csv_name in ['status', 'check_status', 'agent_status', 'last_chk', 'last_agt', 'addr', 'cookie', 'mode', 'algo']:
+	//         type_ = 'string'
+	//     # omitempty has no effect on NullInt64 structs, invalid values are marshaled as null
+	//     omitempty = ',omitempty' if type_ == 'string' else ''
+	//     cog.outl(f"""{struct_name:28s} {type_:9s} `csv:"{csv_name}" json:"{json_name}{omitempty}"`""")
 	// ]]]
-	Pxname                       string `csv:"# pxname" json:"pxname,omitempty"`
-	Svname                       string `csv:"svname" json:"svname,omitempty"`
-	Qcur                         string `csv:"qcur" json:"qcur,omitempty"`
-	Qmax                         string `csv:"qmax" json:"qmax,omitempty"`
-	Scur                         int    `csv:"scur" json:"scur,omitempty"`
-	Smax                         string `csv:"smax" json:"smax,omitempty"`
-	Slim                         int    `csv:"slim" json:"slim,omitempty"`
-	Stot                         string `csv:"stot" json:"stot,omitempty"`
-	Bin                          string `csv:"bin" json:"bin,omitempty"`
-	Bout                         string `csv:"bout" json:"bout,omitempty"`
-	Dreq                         string `csv:"dreq" json:"dreq,omitempty"`
-	Dresp                        string `csv:"dresp" json:"dresp,omitempty"`
-	Ereq                         string `csv:"ereq" json:"ereq,omitempty"`
-	Econ                         string `csv:"econ" json:"econ,omitempty"`
-	Eresp                        string `csv:"eresp" json:"eresp,omitempty"`
-	Wretr                        string `csv:"wretr" json:"wretr,omitempty"`
-	Wredis                       string `csv:"wredis" json:"wredis,omitempty"`
-	Status                       string `csv:"status" json:"status,omitempty"`
-	Weight                       string `csv:"weight" json:"weight,omitempty"`
-	Act                          string `csv:"act" json:"act,omitempty"`
-	Bck                          string `csv:"bck" json:"bck,omitempty"`
-	Chkfail                      string `csv:"chkfail" json:"chkfail,omitempty"`
-	Chkdown                      string `csv:"chkdown" json:"chkdown,omitempty"`
-	Lastchg                      string `csv:"lastchg" json:"lastchg,omitempty"`
-	Downtime                     string `csv:"downtime" json:"downtime,omitempty"`
-	Qlimit                       string `csv:"qlimit" json:"qlimit,omitempty"`
-	Pid                          string `csv:"pid" json:"pid,omitempty"`
-	Iid                          string `csv:"iid" json:"iid,omitempty"`
-	Sid                          string `csv:"sid" json:"sid,omitempty"`
-	Throttle                     string `csv:"throttle" json:"throttle,omitempty"`
-	Lbtot                        string `csv:"lbtot" json:"lbtot,omitempty"`
-	Tracked                      string `csv:"tracked" json:"tracked,omitempty"`
-	Type                         string `csv:"type" json:"type,omitempty"`
-	Rate                         string `csv:"rate" json:"rate,omitempty"`
-	RateLim                      string `csv:"rate_lim" json:"rate_lim,omitempty"`
-	RateMax                      string `csv:"rate_max" json:"rate_max,omitempty"`
-	CheckStatus                  string `csv:"check_status" json:"check_status,omitempty"`
-	CheckCode                    string `csv:"check_code" json:"check_code,omitempty"`
-	CheckDuration                string `csv:"check_duration" json:"check_duration,omitempty"`
-	Hrsp1Xx                      string `csv:"hrsp_1xx" json:"hrsp_1xx,omitempty"`
-	Hrsp2Xx                      string `csv:"hrsp_2xx" json:"hrsp_2xx,omitempty"`
-	Hrsp3Xx                      string `csv:"hrsp_3xx" json:"hrsp_3xx,omitempty"`
-	Hrsp4Xx                      string `csv:"hrsp_4xx" json:"hrsp_4xx,omitempty"`
-	Hrsp5Xx                      string `csv:"hrsp_5xx" json:"hrsp_5xx,omitempty"`
-	HrspOther                    string `csv:"hrsp_other" json:"hrsp_other,omitempty"`
-	Hanafail                     string `csv:"hanafail" json:"hanafail,omitempty"`
-	ReqRate                      string `csv:"req_rate" json:"req_rate,omitempty"`
-	ReqRateMax                   string `csv:"req_rate_max" json:"req_rate_max,omitempty"`
-	ReqTot                       string `csv:"req_tot" json:"req_tot,omitempty"`
-	CliAbrt                      string `csv:"cli_abrt" json:"cli_abrt,omitempty"`
-	SrvAbrt                      string `csv:"srv_abrt" json:"srv_abrt,omitempty"`
-	CompIn                       string `csv:"comp_in" json:"comp_in,omitempty"`
-	CompOut                      string `csv:"comp_out" json:"comp_out,omitempty"`
-	CompByp                      string `csv:"comp_byp" json:"comp_byp,omitempty"`
-	CompRsp                      string `csv:"comp_rsp" json:"comp_rsp,omitempty"`
-	Lastsess                     string `csv:"lastsess" json:"lastsess,omitempty"`
-	LastChk                      string `csv:"last_chk" json:"last_chk,omitempty"`
-	LastAgt                      string `csv:"last_agt" json:"last_agt,omitempty"`
-	Qtime                        string `csv:"qtime" json:"qtime,omitempty"`
-	Ctime                        string `csv:"ctime" json:"ctime,omitempty"`
-	Rtime                        string `csv:"rtime" json:"rtime,omitempty"`
-	Ttime                        string `csv:"ttime" json:"ttime,omitempty"`
-	AgentStatus                  string `csv:"agent_status" json:"agent_status,omitempty"`
-	AgentCode                    string `csv:"agent_code" json:"agent_code,omitempty"`
-	AgentDuration                string `csv:"agent_duration" json:"agent_duration,omitempty"`
-	CheckDesc                    string `csv:"check_desc" json:"check_desc,omitempty"`
-	AgentDesc                    string `csv:"agent_desc" json:"agent_desc,omitempty"`
-	CheckRise                    string `csv:"check_rise" json:"check_rise,omitempty"`
-	CheckFall                    string `csv:"check_fall" json:"check_fall,omitempty"`
-	CheckHealth                  string `csv:"check_health" json:"check_health,omitempty"`
-	AgentRise                    string `csv:"agent_rise" json:"agent_rise,omitempty"`
-	AgentFall                    string `csv:"agent_fall" json:"agent_fall,omitempty"`
-	AgentHealth                  string `csv:"agent_health" json:"agent_health,omitempty"`
-	Addr                         string `csv:"addr" json:"addr,omitempty"`
-	Cookie                       string `csv:"cookie" json:"cookie,omitempty"`
-	Mode                         string `csv:"mode" json:"mode,omitempty"`
-	Algo                         string `csv:"algo" json:"algo,omitempty"`
-	ConnRate                     string `csv:"conn_rate" json:"conn_rate,omitempty"`
-	ConnRateMax                  string `csv:"conn_rate_max" json:"conn_rate_max,omitempty"`
-	ConnTot                      string `csv:"conn_tot" json:"conn_tot,omitempty"`
-	Intercepted                  string `csv:"intercepted" json:"intercepted,omitempty"`
-	Dcon                         string `csv:"dcon" json:"dcon,omitempty"`
-	Dses                         string `csv:"dses" json:"dses,omitempty"`
-	Wrew                         string `csv:"wrew" json:"wrew,omitempty"`
-	Connect                      string `csv:"connect" json:"connect,omitempty"`
-	Reuse                        string `csv:"reuse" json:"reuse,omitempty"`
-	CacheLookups                 string `csv:"cache_lookups" json:"cache_lookups,omitempty"`
-	CacheHits                    string `csv:"cache_hits" json:"cache_hits,omitempty"`
-	SrvIcur                      string `csv:"srv_icur" json:"srv_icur,omitempty"`
-	SrcIlim                      string `csv:"src_ilim" json:"src_ilim,omitempty"`
-	QtimeMax                     string `csv:"qtime_max" json:"qtime_max,omitempty"`
-	CtimeMax                     string `csv:"ctime_max" json:"ctime_max,omitempty"`
-	RtimeMax                     string `csv:"rtime_max" json:"rtime_max,omitempty"`
-	TtimeMax                     string `csv:"ttime_max" json:"ttime_max,omitempty"`
-	Eint                         string `csv:"eint" json:"eint,omitempty"`
-	IdleConnCur                  string `csv:"idle_conn_cur" json:"idle_conn_cur,omitempty"`
-	SafeConnCur                  string `csv:"safe_conn_cur" json:"safe_conn_cur,omitempty"`
-	UsedConnCur                  string `csv:"used_conn_cur" json:"used_conn_cur,omitempty"`
-	NeedConnEst                  string `csv:"need_conn_est" json:"need_conn_est,omitempty"`
-	Uweight                      string `csv:"uweight" json:"uweight,omitempty"`
-	H2HeadersRcvd                string `csv:"h2_headers_rcvd" json:"h2_headers_rcvd,omitempty"`
-	H2DataRcvd                   string `csv:"h2_data_rcvd" json:"h2_data_rcvd,omitempty"`
-	H2SettingsRcvd               string `csv:"h2_settings_rcvd" json:"h2_settings_rcvd,omitempty"`
-	H2RstStreamRcvd              string `csv:"h2_rst_stream_rcvd" json:"h2_rst_stream_rcvd,omitempty"`
-	H2GoawayRcvd                 string `csv:"h2_goaway_rcvd" json:"h2_goaway_rcvd,omitempty"`
-	H2DetectedConnProtocolErrors string `csv:"h2_detected_conn_protocol_errors" json:"h2_detected_conn_protocol_errors,omitempty"`
-	H2DetectedStrmProtocolErrors string `csv:"h2_detected_strm_protocol_errors" json:"h2_detected_strm_protocol_errors,omitempty"`
-	H2RstStreamResp              string `csv:"h2_rst_stream_resp" json:"h2_rst_stream_resp,omitempty"`
-	H2GoawayResp                 string `csv:"h2_goaway_resp" json:"h2_goaway_resp,omitempty"`
-	H2OpenConnections            string `csv:"h2_open_connections" json:"h2_open_connections,omitempty"`
-	H2BackendOpenStreams         string `csv:"h2_backend_open_streams" json:"h2_backend_open_streams,omitempty"`
-	// [[[end]]] (checksum: 0317562b38ec931796a67e29935fb72f)
+	Pxname                       string    `csv:"# pxname" json:"pxname,omitempty"`
+	Svname                       string    `csv:"svname" json:"svname,omitempty"`
+	Qcur                         NullInt64 `csv:"qcur" json:"qcur"`
+	Qmax                         NullInt64 `csv:"qmax" json:"qmax"`
+	Scur                         NullInt64 `csv:"scur" json:"scur"`
+	Smax                         NullInt64 `csv:"smax" json:"smax"`
+	Slim                         NullInt64 `csv:"slim" json:"slim"`
+	Stot                         NullInt64 `csv:"stot" json:"stot"`
+	Bin                          NullInt64 `csv:"bin" json:"bin"`
+	Bout                         NullInt64 `csv:"bout" json:"bout"`
+	Dreq                         NullInt64 `csv:"dreq" json:"dreq"`
+	Dresp                        NullInt64 `csv:"dresp" json:"dresp"`
+	Ereq                         NullInt64 `csv:"ereq" json:"ereq"`
+	Econ                         NullInt64 `csv:"econ" json:"econ"`
+	Eresp                        NullInt64 `csv:"eresp" json:"eresp"`
+	Wretr                        NullInt64 `csv:"wretr" json:"wretr"`
+	Wredis                       NullInt64 `csv:"wredis" json:"wredis"`
+	Status                       string    `csv:"status" json:"status,omitempty"`
+	Weight                       NullInt64 `csv:"weight" json:"weight"`
+	Act                          NullInt64 `csv:"act" json:"act"`
+	Bck                          NullInt64 `csv:"bck" json:"bck"`
+	Chkfail                      NullInt64 `csv:"chkfail" json:"chkfail"`
+	Chkdown                      NullInt64 `csv:"chkdown" json:"chkdown"`
+	Lastchg                      NullInt64 `csv:"lastchg" json:"lastchg"`
+	Downtime                     NullInt64 `csv:"downtime" json:"downtime"`
+	Qlimit                       NullInt64 `csv:"qlimit" json:"qlimit"`
+	Pid                          NullInt64 `csv:"pid" json:"pid"`
+	Iid                          NullInt64 `csv:"iid" json:"iid"`
+	Sid                          NullInt64 `csv:"sid" json:"sid"`
+	Throttle                     NullInt64 `csv:"throttle" json:"throttle"`
+	Lbtot                        NullInt64 `csv:"lbtot" json:"lbtot"`
+	Tracked                      NullInt64 `csv:"tracked" json:"tracked"`
+	Type                         NullInt64 `csv:"type" json:"type"`
+	Rate                         NullInt64 `csv:"rate" json:"rate"`
+	RateLim                      NullInt64 `csv:"rate_lim" json:"rate_lim"`
+	RateMax                      NullInt64 `csv:"rate_max" json:"rate_max"`
+	CheckStatus                  string    `csv:"check_status" json:"check_status,omitempty"`
+	CheckCode                    NullInt64 `csv:"check_code" json:"check_code"`
+	CheckDuration                NullInt64 `csv:"check_duration" json:"check_duration"`
+	Hrsp1Xx                      NullInt64 `csv:"hrsp_1xx" json:"hrsp_1xx"`
+	Hrsp2Xx                      NullInt64 `csv:"hrsp_2xx" json:"hrsp_2xx"`
+	Hrsp3Xx                      NullInt64 `csv:"hrsp_3xx" json:"hrsp_3xx"`
+	Hrsp4Xx                      NullInt64 `csv:"hrsp_4xx" json:"hrsp_4xx"`
+	Hrsp5Xx                      NullInt64 `csv:"hrsp_5xx" json:"hrsp_5xx"`
+	HrspOther                    NullInt64 `csv:"hrsp_other" json:"hrsp_other"`
+	Hanafail                     NullInt64 `csv:"hanafail" json:"hanafail"`
+	ReqRate                      NullInt64 `csv:"req_rate" json:"req_rate"`
+	ReqRateMax                   NullInt64 `csv:"req_rate_max" json:"req_rate_max"`
+	ReqTot                       NullInt64 `csv:"req_tot" json:"req_tot"`
+	CliAbrt                      NullInt64 `csv:"cli_abrt" json:"cli_abrt"`
+	SrvAbrt                      NullInt64 `csv:"srv_abrt" json:"srv_abrt"`
+	CompIn                       NullInt64 `csv:"comp_in" json:"comp_in"`
+	CompOut                      NullInt64 `csv:"comp_out" json:"comp_out"`
+	CompByp                      NullInt64 `csv:"comp_byp" json:"comp_byp"`
+	CompRsp                      NullInt64 `csv:"comp_rsp" json:"comp_rsp"`
+	Lastsess                     NullInt64 `csv:"lastsess" json:"lastsess"`
+	LastChk                      string    `csv:"last_chk" json:"last_chk,omitempty"`
+	LastAgt                      string    `csv:"last_agt" json:"last_agt,omitempty"`
+	Qtime                        NullInt64 `csv:"qtime" json:"qtime"`
+	Ctime                        NullInt64 `csv:"ctime" json:"ctime"`
+	Rtime                        NullInt64 `csv:"rtime" json:"rtime"`
+	Ttime                        NullInt64 `csv:"ttime" json:"ttime"`
+	AgentStatus                  string    `csv:"agent_status" json:"agent_status,omitempty"`
+	AgentCode                    NullInt64 `csv:"agent_code" json:"agent_code"`
+	AgentDuration                NullInt64 `csv:"agent_duration" json:"agent_duration"`
+	CheckDesc                    string    `csv:"check_desc" json:"check_desc,omitempty"`
+	AgentDesc                    string    `csv:"agent_desc" json:"agent_desc,omitempty"`
+	CheckRise                    NullInt64 `csv:"check_rise" json:"check_rise"`
+	CheckFall                    NullInt64 `csv:"check_fall" json:"check_fall"`
+	CheckHealth                  NullInt64 `csv:"check_health" json:"check_health"`
+	AgentRise                    NullInt64 `csv:"agent_rise" json:"agent_rise"`
+	AgentFall                    NullInt64 `csv:"agent_fall" json:"agent_fall"`
+	AgentHealth                  NullInt64 `csv:"agent_health" json:"agent_health"`
+	Addr                         string    `csv:"addr" json:"addr,omitempty"`
+	Cookie                       string    `csv:"cookie" json:"cookie,omitempty"`
+	Mode                         string    `csv:"mode" json:"mode,omitempty"`
+	Algo                         string    `csv:"algo" json:"algo,omitempty"`
+	ConnRate                     NullInt64 `csv:"conn_rate" json:"conn_rate"`
+	ConnRateMax                  NullInt64 `csv:"conn_rate_max" json:"conn_rate_max"`
+	ConnTot                      NullInt64 `csv:"conn_tot" json:"conn_tot"`
+	Intercepted                  NullInt64 `csv:"intercepted" json:"intercepted"`
+	Dcon                         NullInt64 `csv:"dcon" json:"dcon"`
+	Dses                         NullInt64 `csv:"dses" json:"dses"`
+	Wrew                         NullInt64 `csv:"wrew" json:"wrew"`
+	Connect                      NullInt64 `csv:"connect" json:"connect"`
+	Reuse                        NullInt64 `csv:"reuse" json:"reuse"`
+	CacheLookups                 NullInt64 `csv:"cache_lookups" json:"cache_lookups"`
+	CacheHits                    NullInt64 `csv:"cache_hits" json:"cache_hits"`
+	SrvIcur                      NullInt64 `csv:"srv_icur" json:"srv_icur"`
+	SrcIlim                      NullInt64 `csv:"src_ilim" json:"src_ilim"`
+	QtimeMax                     NullInt64 `csv:"qtime_max" json:"qtime_max"`
+	CtimeMax                     NullInt64 `csv:"ctime_max" json:"ctime_max"`
+	RtimeMax                     NullInt64 `csv:"rtime_max" json:"rtime_max"`
+	TtimeMax                     NullInt64 `csv:"ttime_max" json:"ttime_max"`
+	Eint                         NullInt64 `csv:"eint" json:"eint"`
+	IdleConnCur                  NullInt64 `csv:"idle_conn_cur" json:"idle_conn_cur"`
+	SafeConnCur                  NullInt64 `csv:"safe_conn_cur" json:"safe_conn_cur"`
+	UsedConnCur                  NullInt64 `csv:"used_conn_cur" json:"used_conn_cur"`
+	NeedConnEst                  NullInt64 `csv:"need_conn_est" json:"need_conn_est"`
+	Uweight                      NullInt64 `csv:"uweight" json:"uweight"`
+	H2HeadersRcvd                NullInt64 `csv:"h2_headers_rcvd" json:"h2_headers_rcvd"`
+	H2DataRcvd                   NullInt64 `csv:"h2_data_rcvd" json:"h2_data_rcvd"`
+	H2SettingsRcvd               NullInt64 `csv:"h2_settings_rcvd" json:"h2_settings_rcvd"`
+	H2RstStreamRcvd              NullInt64 `csv:"h2_rst_stream_rcvd" json:"h2_rst_stream_rcvd"`
+	H2GoawayRcvd                 NullInt64 `csv:"h2_goaway_rcvd" json:"h2_goaway_rcvd"`
+	H2DetectedConnProtocolErrors NullInt64 `csv:"h2_detected_conn_protocol_errors" json:"h2_detected_conn_protocol_errors"`
+	H2DetectedStrmProtocolErrors NullInt64 `csv:"h2_detected_strm_protocol_errors" json:"h2_detected_strm_protocol_errors"`
+	H2RstStreamResp              NullInt64 `csv:"h2_rst_stream_resp" json:"h2_rst_stream_resp"`
+	H2GoawayResp                 NullInt64 `csv:"h2_goaway_resp" json:"h2_goaway_resp"`
+	H2OpenConnections            NullInt64 `csv:"h2_open_connections" json:"h2_open_connections"`
+	H2BackendOpenStreams         NullInt64 `csv:"h2_backend_open_streams" json:"h2_backend_open_streams"`
+	// [[[end]]] (checksum: 557956e5f8249193eeaa04fa69bf6dd6)
 }
 
 // StatService is a mapping of all SvName lines
@@ -169,8 +180,14 @@ func ParseStatCSV(data io.Reader) (Stats, []byte, error) {
 		return nil, nil, fmt.Errorf("csv parse error: %w", err)
 	}
 
+	return statsFromLines(lines), rawData, nil
+}
+
+// statsFromLines groups lines by pxname and svname,
+// lines of the same object from several processes are aggregated.
+func statsFromLines(lines []StatLine) Stats {
 	out := make(Stats)
-	for _, line := range lines {
+	for _, line := range AggregateLines(lines) {
 		pxmap, ok := out[line.Pxname]
 		if !ok {
 			pxmap = make(StatService)
@@ -180,30 +197,17 @@ func ParseStatCSV(data io.Reader) (Stats, []byte, error) {
 		pxmap[line.Svname] = line
 	}
 
-	return out, rawData, nil
+	return out
 }
 
-// GetStats query HAProxy for Stats
-func GetStats(socketPath string) (Stats, []byte, error) {
-	addr := &net.UnixAddr{Name: socketPath}
-	sock, err := net.DialUnix("unix", nil, addr)
-	if err != nil {
-		return nil, nil, fmt.Errorf("socket open error: %w", err)
-	}
-	defer sock.Close()
-
-	// Suggest that IO shouldn't ever reach so long timeout
-	err = sock.SetDeadline(time.Now().Add(time.Second))
-	if err != nil {
-		return nil, nil, fmt.Errorf("socket set deadline error: %w", err)
-	}
-
-	_, err = sock.Write([]byte("show stat\n"))
+// GetStats query HAProxy for Stats using "show stat" output format
+func GetStats(d *Dialer, format StatFormat) (Stats, []byte, error) {
+	data, err := d.Exec(format.Command())
 	if err != nil {
-		return nil, nil, fmt.Errorf("socket request error: %w", err)
+		return nil, nil, err
 	}
 
-	return ParseStatCSV(sock)
+	return ParseStats(format, bytes.NewReader(data))
 }
 
 // IsUp checks that status of the service is up
@@ -227,6 +231,28 @@ func (l StatLine) IsUp(backend *StatLine) bool {
 		l.Status == "DRAIN")
 }
 
+// BaseStatus returns the status without details: "MAINT (via px/sv)" is MAINT.
+// transitional reports "UP n/m" and "DOWN n/m" states, when health checks are going to change the state.
+func (l StatLine) BaseStatus() (status string, transitional bool) {
+	status = l.Status
+	if i := strings.IndexByte(status, '('); i > 0 {
+		status = strings.TrimSpace(status[:i])
+	}
+
+	if i := strings.LastIndexByte(status, ' '); i > 0 && transitionRe.MatchString(status[i+1:]) {
+		return status[:i], true
+	}
+
+	return status, false
+}
+
+var transitionRe = regexp.MustCompile(`^\d+/\d+$`)
+
+// IsBackup checks that the server is a backup server
+func (l StatLine) IsBackup() bool {
+	return l.Bck.Or(0) > 0
+}
+
 // LogName make a name for check logs
 func (l StatLine) LogName() string {
 	if l.CheckStatus == "" {
@@ -236,19 +262,80 @@ func (l StatLine) LogName() string {
 	return fmt.Sprintf("%s/%s[%s]", l.Pxname, l.Svname, l.CheckStatus)
 }
 
+// HasSessionLimit reports if slim is set for the entry
+func (l StatLine) HasSessionLimit() bool {
+	return l.Slim.Or(0) > 0
+}
+
 // SessionLimitPercentage calculates percentage usage of sessions limit
 func (l StatLine) SessionLimitPercentage() float32 {
-	return 100.0 * float32(l.Scur) / float32(l.Slim)
+	return 100.0 * l.Scur.Float32() / l.Slim.Float32()
 }
 
-// Servers makes a copy of StatService without frontend and backend entries
+// IsListener checks that the entry is a frontend listener (option socket-stats)
+func (l StatLine) IsListener() bool {
+	return l.Type.Or(-1) == TypeListener
+}
+
+// TypeName returns lowercase name of the entry type: frontend, backend, server or listener
+func (l StatLine) TypeName() string {
+	switch l.Type.Or(-1) {
+	case TypeFrontend:
+		return "frontend"
+	case TypeBackend:
+		return "backend"
+	case TypeServer:
+		return "server"
+	case TypeListener:
+		return "listener"
+	default:
+		return ""
+	}
+}
+
+// Values returns set numeric columns by csv name
+func (l StatLine) Values() map[string]int64 {
+	ret := make(map[string]int64)
+	v := reflect.ValueOf(l)
+	for name, fi := range statFieldIndex {
+		n, ok := v.Field(fi).Interface().(NullInt64)
+		if ok && n.Valid {
+			ret[name] = n.Int64
+		}
+	}
+
+	return ret
+}
+
+// Servers makes a copy of StatService without frontend, backend and listener entries
 func (s StatService) Servers() StatService {
 	return s.Filter(func(s StatLine) bool {
 		// XXX(vermakov): we also filter empty Svname because that must be an error in HAproxy 2.3.0+
-		return s.Svname != Frontend && s.Svname != Backend && s.Svname != ""
+		return s.Svname != Frontend && s.Svname != Backend && s.Svname != "" && !s.IsListener()
 	})
 }
 
+// Listeners makes a copy of StatService with FRONTEND and listener entries
+func (s StatService) Listeners() StatService {
+	return s.Filter(func(s StatLine) bool {
+		return s.Svname == Frontend || s.IsListener()
+	})
+}
+
+// Lines returns entries sorted by Svname
+func (s StatService) Lines() []StatLine {
+	ret := make([]StatLine, 0, len(s))
+	for _, value := range s {
+		ret = append(ret, value)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Svname < ret[j].Svname
+	})
+
+	return ret
+}
+
 // Filter return entries which passes the testFunc
 func (s StatService) Filter(testFunc func(StatLine) bool) StatService {
 	ret := make(StatService)
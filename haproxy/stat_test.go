@@ -1,13 +1,9 @@
 package haproxy
 
 import (
-	"context"
-	"net"
-	"os"
 	"path/filepath"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -35,73 +31,89 @@ func TestParseStatCSV(t *testing.T) {
 	assert.NoError(err)
 	assert.Len(stats, 4)
 
+	fe := stats["https"][Frontend]
+	assert.Equal(NewInt64(100000), fe.Slim)
+	assert.Equal(NewInt64(94972540), fe.Bout)
+	assert.Equal(NullInt64{}, fe.Qcur)
+
+	srv := stats["ipmi_exporter"]["ctrl03"]
+	assert.Equal(NewInt64(1683), srv.Stot)
+	assert.Equal(NewInt64(1642), srv.Ttime)
+	assert.Equal(NewInt64(11225), srv.Lastchg)
+	assert.Equal("L7OK", srv.CheckStatus)
+	assert.False(srv.HasSessionLimit())
+
+	px := stats["ipmi_exporter"]
+	assert.Len(px.Servers(), 3)
+	assert.Len(px.Listeners(), 1)
+	lines := px.Lines()
+	assert.Len(lines, 5)
+	assert.Equal(Backend, lines[0].Svname)
+	assert.Equal("ctrl03", lines[4].Svname)
+
 	//t.Log(stats)
 }
 
 func TestGetStats(t *testing.T) {
 	assert := assert.New(t)
-	tempDir := t.TempDir()
-
-	socketPath := filepath.Join(tempDir, "haproxy.sock")
-
-	serverCtx, serverCf := context.WithCancel(context.TODO())
-	defer serverCf()
-
-	serverReady := make(chan bool, 1)
-
-	go func() {
-		addr := &net.UnixAddr{Name: socketPath}
-		ln, err := net.ListenUnix("unix", addr)
-		assert.NoError(err)
-		defer ln.Close()
-		serverReady <- true
-
-		for {
-			select {
-			case <-serverCtx.Done():
-				t.Log("socket server terminated")
-				return
-
-			default:
-				if err := ln.SetDeadline(time.Now().Add(time.Second)); err != nil {
-					// t.Fatal(err)
-					assert.NoError(err)
-					return
-				}
-
-				fd, err := ln.Accept()
-				if err != nil {
-					if os.IsTimeout(err) {
-						continue
-					}
-
-					// t.Fatal(err)
-					assert.NoError(err)
-				}
-
-				go func(c net.Conn) {
-					defer c.Close()
 
-					buf := make([]byte, 1024)
+	socketPath := filepath.Join(t.TempDir(), "haproxy.sock")
+	serveRuntimeAPI(t, "unix", socketPath, map[string]string{
+		"show stat": testingCSV,
+	})
 
-					nr, err := c.Read(buf)
-					assert.NoError(err)
+	stats, _, err := GetStats(&Dialer{Network: "unix", Address: socketPath}, StatFormatCSV)
+	assert.NoError(err)
+	assert.Len(stats, 4)
 
-					cmd := string(buf[0:nr])
-					assert.Equal("show stat\n", cmd)
+	//t.Log(stats)
+}
 
-					_, err = c.Write([]byte(testingCSV))
-					assert.NoError(err)
-				}(fd)
-			}
-		}
-	}()
+func TestStatLineValues(t *testing.T) {
+	assert := assert.New(t)
 
-	<-serverReady
+	stats, _, err := ParseStatCSV(strings.NewReader(testingCSV))
+	if !assert.NoError(err) {
+		return
+	}
+
+	fe := stats["ipmi_exporter"][Frontend]
+	assert.Equal("frontend", fe.TypeName())
+	assert.Equal("server", stats["ipmi_exporter"]["ctrl01"].TypeName())
+	assert.Equal("backend", stats["ipmi_exporter"][Backend].TypeName())
+
+	values := fe.Values()
+	assert.Equal(int64(6), values["scur"])
+	assert.Equal(int64(100000), values["slim"])
+	assert.Equal(int64(5049), values["hrsp_2xx"])
+	assert.NotContains(values, "qcur")
+	assert.NotContains(values, "pxname")
+}
 
-	stats, _, err := GetStats(socketPath)
-	assert.NoError(err)
-	assert.Len(stats, 4)
+func TestStatLineBaseStatus(t *testing.T) {
+	assert := assert.New(t)
 
-	//t.Log(stats)
+	for status, expected := range map[string]struct {
+		Status       string
+		Transitional bool
+	}{
+		"UP":                  {"UP", false},
+		"UP 1/3":              {"UP", true},
+		"DOWN 1/2":            {"DOWN", true},
+		"NOLB 2/3":            {"NOLB", true},
+		"MAINT":               {"MAINT", false},
+		"MAINT (via bk/srv1)": {"MAINT", false},
+		"MAINT (resolution)":  {"MAINT", false},
+		"DRAIN (agent)":       {"DRAIN", false},
+		"no check":            {"no check", false},
+		"":                    {"", false},
+	} {
+		s, transitional := StatLine{Status: status}.BaseStatus()
+		assert.Equal(expected.Status, s, status)
+		assert.Equal(expected.Transitional, transitional, status)
+	}
+
+	assert.True(StatLine{Bck: NewInt64(1)}.IsBackup())
+	assert.False(StatLine{Bck: NewInt64(0)}.IsBackup())
+	assert.False(StatLine{}.IsBackup())
 }
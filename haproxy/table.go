@@ -0,0 +1,249 @@
+package haproxy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Table is a stick table reported by "show table"
+type Table struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Size int64  `json:"size"`
+	Used int64  `json:"used"`
+}
+
+// UsagePercentage calculates percentage usage of the table size
+func (t Table) UsagePercentage() float32 {
+	if t.Size <= 0 {
+		return 0
+	}
+
+	return 100.0 * float32(t.Used) / float32(t.Size)
+}
+
+// TableEntry is an entry of a stick table reported by "show table <name>".
+// Data keys are stored data types without the period, e.g. http_req_rate.
+type TableEntry struct {
+	ID   string            `json:"id"`
+	Key  string            `json:"key"`
+	Use  int64             `json:"use"`
+	Exp  int64             `json:"exp"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// Counter returns the numeric value of the stored data type
+func (e TableEntry) Counter(name string) (int64, bool) {
+	v, ok := e.Data[name]
+	if !ok {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	return n, err == nil
+}
+
+// parseTableHeader parses "# table: front_pub, type: ip, size:204800, used:171454"
+func parseTableHeader(line string) (Table, error) {
+	t := Table{}
+	for _, part := range strings.Split(strings.TrimPrefix(line, "#"), ",") {
+		name, value, ok := strings.Cut(part, ":")
+		if !ok {
+			return t, fmt.Errorf("malformed header: %q", line)
+		}
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch strings.TrimSpace(name) {
+		case "table":
+			t.Name = value
+		case "type":
+			t.Type = value
+		case "size":
+			t.Size, err = strconv.ParseInt(value, 10, 64)
+		case "used":
+			t.Used, err = strconv.ParseInt(value, 10, 64)
+		}
+		if err != nil {
+			return t, fmt.Errorf("%s: %w", strings.TrimSpace(name), err)
+		}
+	}
+
+	if t.Name == "" {
+		return t, fmt.Errorf("malformed header: %q", line)
+	}
+
+	return t, nil
+}
+
+// parseTableEntry parses "0x55d4a9f1c0d0: key=127.0.0.1 use=0 exp=3422 gpc0=1 http_req_rate(10000)=3"
+func parseTableEntry(line string) (TableEntry, error) {
+	id, rest, ok := strings.Cut(line, ": ")
+	if !ok {
+		return TableEntry{}, fmt.Errorf("malformed entry: %q", line)
+	}
+
+	e := TableEntry{ID: id, Data: make(map[string]string)}
+	for _, field := range strings.Fields(rest) {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return e, fmt.Errorf("malformed entry: %q", line)
+		}
+
+		var err error
+		switch name {
+		case "key":
+			e.Key = value
+		case "use":
+			e.Use, err = strconv.ParseInt(value, 10, 64)
+		case "exp":
+			e.Exp, err = strconv.ParseInt(value, 10, 64)
+		default:
+			// http_req_rate(10000) is stored as http_req_rate
+			if i := strings.IndexByte(name, '('); i > 0 {
+				name = name[:i]
+			}
+			e.Data[name] = value
+		}
+		if err != nil {
+			return e, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return e, nil
+}
+
+// ParseTables parses "show table" output:
+//
+//	# table: front_pub, type: ip, size:204800, used:171454
+//	# table: back_rdp, type: ip, size:204800, used:0
+func ParseTables(data io.Reader) ([]Table, error) {
+	ret := make([]Table, 0)
+
+	scanner := bufio.NewScanner(data)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		t, err := parseTableHeader(line)
+		if err != nil {
+			return nil, fmt.Errorf("table parse error: line %d: %w", lineNo, err)
+		}
+		ret = append(ret, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read error: %w", err)
+	}
+
+	return ret, nil
+}
+
+// ParseTableEntries parses "show table <name>" output:
+//
+//	# table: front_pub, type: ip, size:204800, used:2
+//	0x55d4a9f1c0d0: key=10.0.0.1 use=0 exp=3422 http_req_rate(10000)=3
+//	0x55d4a9f1c1e0: key=10.0.0.2 use=0 exp=9021 http_req_rate(10000)=120
+func ParseTableEntries(data io.Reader) (*Table, []TableEntry, error) {
+	var table *Table
+	ret := make([]TableEntry, 0)
+
+	scanner := bufio.NewScanner(data)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			t, err := parseTableHeader(line)
+			if err != nil {
+				return nil, nil, fmt.Errorf("table parse error: line %d: %w", lineNo, err)
+			}
+			table = &t
+			continue
+		}
+
+		e, err := parseTableEntry(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("table parse error: line %d: %w", lineNo, err)
+		}
+		ret = append(ret, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("read error: %w", err)
+	}
+
+	if table == nil {
+		return nil, nil, fmt.Errorf("table parse error: no table header")
+	}
+
+	return table, ret, nil
+}
+
+// GetTables query HAProxy for stick tables
+func GetTables(e Executor) ([]Table, []byte, error) {
+	data, err := e.Exec("show table")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tables, err := ParseTables(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tables, data, nil
+}
+
+// GetTableEntries query HAProxy for all entries of the stick table
+func GetTableEntries(e Executor, name string) ([]TableEntry, []byte, error) {
+	return getTableEntries(e, "show table "+name)
+}
+
+// ErrDataTypeNotStored is returned when the stick table does not store the data type used in the filter
+var ErrDataTypeNotStored = errors.New("data type is not stored in the table")
+
+// tableNoDataType is the runtime API reply to a filter on the data type which the table does not store
+const tableNoDataType = "Data type not stored in this table"
+
+// GetTableEntriesOver query HAProxy for entries of the stick table which stored data type is over the value.
+// The filter is applied by HAProxy, so entries of a large table are not sent all.
+func GetTableEntriesOver(e Executor, name, dataType string, value int64) ([]TableEntry, []byte, error) {
+	entries, data, err := getTableEntries(e, fmt.Sprintf("show table %s data.%s gt %d", name, dataType, value))
+	if errors.Is(err, ErrDataTypeNotStored) {
+		return nil, nil, fmt.Errorf("%s: %w", dataType, err)
+	}
+
+	return entries, data, err
+}
+
+func getTableEntries(e Executor, cmd string) ([]TableEntry, []byte, error) {
+	data, err := e.Exec(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// errors are replied as a text line instead of the table header
+	if reply := bytes.TrimSpace(data); len(reply) > 0 && reply[0] != '#' {
+		if bytes.HasPrefix(reply, []byte(tableNoDataType)) {
+			return nil, nil, ErrDataTypeNotStored
+		}
+
+		line, _, _ := bytes.Cut(reply, []byte("\n"))
+		return nil, nil, fmt.Errorf("runtime API error: %s", line)
+	}
+
+	_, entries, err := ParseTableEntries(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return entries, data, nil
+}
@@ -0,0 +1,106 @@
+package haproxy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testingTables = `# table: front_pub, type: ip, size:204800, used:171454
+# table: back_rdp, type: ip, size:204800, used:0
+
+`
+
+const testingTableEntries = `# table: front_pub, type: ip, size:204800, used:3
+0x55d4a9f1c0d0: key=10.0.0.1 use=0 exp=3422 shard=0 gpc0=1 http_req_rate(10000)=3
+0x55d4a9f1c1e0: key=10.0.0.2 use=1 exp=9021 shard=0 gpc0=0 http_req_rate(10000)=120
+0x55d4a9f1c2f0: key=10.0.0.3 use=0 exp=120 shard=0 server_name=srv1
+
+`
+
+func TestParseTables(t *testing.T) {
+	assert := assert.New(t)
+
+	tables, err := ParseTables(strings.NewReader(testingTables))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal([]Table{
+		{Name: "front_pub", Type: "ip", Size: 204800, Used: 171454},
+		{Name: "back_rdp", Type: "ip", Size: 204800, Used: 0},
+	}, tables)
+	assert.InDelta(83.7, tables[0].UsagePercentage(), 0.1)
+	assert.Equal(float32(0), Table{}.UsagePercentage())
+
+	_, err = ParseTables(strings.NewReader("# table: x, size:abc\n"))
+	assert.Error(err)
+}
+
+func TestParseTableEntries(t *testing.T) {
+	assert := assert.New(t)
+
+	table, entries, err := ParseTableEntries(strings.NewReader(testingTableEntries))
+	if !assert.NoError(err) || !assert.Len(entries, 3) {
+		return
+	}
+	assert.Equal("front_pub", table.Name)
+	assert.Equal("10.0.0.2", entries[1].Key)
+	assert.Equal(int64(1), entries[1].Use)
+	assert.Equal(int64(9021), entries[1].Exp)
+
+	v, ok := entries[1].Counter("http_req_rate")
+	assert.True(ok)
+	assert.Equal(int64(120), v)
+
+	_, ok = entries[2].Counter("http_req_rate")
+	assert.False(ok)
+	_, ok = entries[2].Counter("server_name")
+	assert.False(ok)
+	assert.Equal("srv1", entries[2].Data["server_name"])
+
+	_, _, err = ParseTableEntries(strings.NewReader("No such table: abc\n"))
+	assert.Error(err)
+}
+
+func TestGetTables(t *testing.T) {
+	assert := assert.New(t)
+
+	socketPath := filepath.Join(t.TempDir(), "master.sock")
+	serveRuntimeAPI(t, "unix", socketPath, map[string]string{
+		"show proc":                   testingProc,
+		"@!1192 show table":           testingTables,
+		"@!1192 show table front_pub": testingTableEntries,
+		"@!1192 show table front_pub data.http_req_rate gt 0": testingTableEntries,
+		"@!1192 show table front_pub data.conn_cnt gt 0":      "Data type not stored in this table\n",
+		"@!1192 show table abc":                               "No such table: abc\n",
+	})
+
+	w, err := CurrentWorker(&Dialer{Network: "unix", Address: socketPath})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(1192, w.PID)
+
+	tables, _, err := GetTables(w)
+	if assert.NoError(err) {
+		assert.Len(tables, 2)
+	}
+
+	entries, _, err := GetTableEntries(w, "front_pub")
+	if assert.NoError(err) {
+		assert.Len(entries, 3)
+	}
+	entries, _, err = GetTableEntriesOver(w, "front_pub", "http_req_rate", 0)
+	if assert.NoError(err) {
+		assert.Len(entries, 3)
+	}
+
+	_, _, err = GetTableEntriesOver(w, "front_pub", "conn_cnt", 0)
+	assert.ErrorIs(err, ErrDataTypeNotStored)
+	assert.EqualError(err, "conn_cnt: data type is not stored in the table")
+
+	_, _, err = GetTableEntries(w, "abc")
+	assert.EqualError(err, "runtime API error: No such table: abc")
+}
@@ -0,0 +1,287 @@
+package haproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// StatFormat is the output format of "show stat"
+type StatFormat string
+
+const (
+	// StatFormatCSV is the default "show stat" output
+	StatFormatCSV StatFormat = "csv"
+	// StatFormatTyped is "show stat typed" output
+	StatFormatTyped StatFormat = "typed"
+	// StatFormatJSON is "show stat json" output
+	StatFormatJSON StatFormat = "json"
+)
+
+// Command returns runtime API command for the format
+func (f StatFormat) Command() string {
+	switch f {
+	case StatFormatTyped, StatFormatJSON:
+		return "show stat " + string(f)
+	default:
+		return "show stat"
+	}
+}
+
+// Field is one value of "show stat typed" or "show stat json" output
+type Field struct {
+	ObjType string
+	ProxyID int
+	ID      int
+	Pos     int
+	Name    string
+	Process int
+	Origin  string
+	Nature  string
+	Scope   string
+	Type    string
+	Value   string
+}
+
+// objectKey identifies the stat object the field belongs to
+func (f Field) objectKey() string {
+	return fmt.Sprintf("%s.%d.%d.%d", f.ObjType, f.ProxyID, f.ID, f.Process)
+}
+
+// ParseStats parses "show stat" output of the given format into Stats
+func ParseStats(format StatFormat, data io.Reader) (Stats, []byte, error) {
+	switch format {
+	case StatFormatCSV, "":
+		return ParseStatCSV(data)
+	case StatFormatTyped:
+		return ParseStatTyped(data)
+	case StatFormatJSON:
+		return ParseStatJSON(data)
+	default:
+		return nil, nil, fmt.Errorf("unsupported stat format: %s", format)
+	}
+}
+
+// ParseStatTyped parses "show stat typed" output into Stats
+//
+// Each line looks like:
+//
+//	F.2.0.0.pxname.1:KNS:str:http
+//	<objtype>.<proxy id>.<object id>.<field pos>.<field name>.<process>:<origin><nature><scope>:<type>:<value>
+func ParseStatTyped(data io.Reader) (Stats, []byte, error) {
+	rawData, err := io.ReadAll(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read error: %w", err)
+	}
+
+	fields := make([]Field, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(rawData))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		f, err := parseTypedField(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("typed parse error: line %d: %w", lineNo, err)
+		}
+
+		fields = append(fields, f)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("read error: %w", err)
+	}
+
+	lines, err := fieldsToLines(fields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("typed parse error: %w", err)
+	}
+
+	return statsFromLines(lines), rawData, nil
+}
+
+func parseTypedField(line string) (Field, error) {
+	parts := strings.SplitN(line, ":", 4)
+	if len(parts) != 4 {
+		return Field{}, fmt.Errorf("malformed line: %q", line)
+	}
+
+	key := strings.Split(parts[0], ".")
+	if len(key) != 6 {
+		return Field{}, fmt.Errorf("malformed field key: %q", parts[0])
+	}
+
+	ints := make([]int, 0, 4)
+	for _, s := range []string{key[1], key[2], key[3], key[5]} {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return Field{}, fmt.Errorf("malformed field key: %q: %w", parts[0], err)
+		}
+		ints = append(ints, v)
+	}
+
+	f := Field{
+		ObjType: key[0],
+		ProxyID: ints[0],
+		ID:      ints[1],
+		Pos:     ints[2],
+		Name:    key[4],
+		Process: ints[3],
+		Type:    parts[2],
+		Value:   parts[3],
+	}
+
+	tags := parts[1]
+	for i, dst := range []*string{&f.Origin, &f.Nature, &f.Scope} {
+		if i < len(tags) {
+			*dst = tags[i : i+1]
+		}
+	}
+
+	return f, nil
+}
+
+// jsonField is one entry of "show stat json" output
+type jsonField struct {
+	ObjType string `json:"objType"`
+	ProxyID int    `json:"proxyId"`
+	ID      int    `json:"id"`
+	Field   struct {
+		Pos  int    `json:"pos"`
+		Name string `json:"name"`
+	} `json:"field"`
+	ProcessNum int `json:"processNum"`
+	Tags       struct {
+		Origin string `json:"origin"`
+		Nature string `json:"nature"`
+		Scope  string `json:"scope"`
+	} `json:"tags"`
+	Value struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	} `json:"value"`
+}
+
+// ParseStatJSON parses "show stat json" output into Stats
+func ParseStatJSON(data io.Reader) (Stats, []byte, error) {
+	rawData, err := io.ReadAll(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read error: %w", err)
+	}
+
+	objects := [][]jsonField{}
+	err = json.Unmarshal(rawData, &objects)
+	if err != nil {
+		return nil, nil, fmt.Errorf("json parse error: %w", err)
+	}
+
+	fields := make([]Field, 0)
+	for _, obj := range objects {
+		for _, jf := range obj {
+			f := Field{
+				ObjType: jf.ObjType,
+				ProxyID: jf.ProxyID,
+				ID:      jf.ID,
+				Pos:     jf.Field.Pos,
+				Name:    jf.Field.Name,
+				Process: jf.ProcessNum,
+				Origin:  jf.Tags.Origin,
+				Nature:  jf.Tags.Nature,
+				Scope:   jf.Tags.Scope,
+				Type:    jf.Value.Type,
+			}
+
+			var s string
+			if err := json.Unmarshal(jf.Value.Value, &s); err == nil {
+				f.Value = s
+			} else {
+				f.Value = string(jf.Value.Value)
+			}
+
+			fields = append(fields, f)
+		}
+	}
+
+	lines, err := fieldsToLines(fields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("json parse error: %w", err)
+	}
+
+	return statsFromLines(lines), rawData, nil
+}
+
+// statFieldIndex maps csv column name to StatLine field index
+var statFieldIndex = func() map[string]int {
+	ret := make(map[string]int)
+	t := reflect.TypeOf(StatLine{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.TrimPrefix(t.Field(i).Tag.Get("csv"), "# ")
+		if name != "" {
+			ret[name] = i
+		}
+	}
+
+	return ret
+}()
+
+// fieldsToLines groups fields by object and fills StatLine from them.
+// Fields unknown to StatLine are ignored, so new HAProxy columns do not break parsing.
+func fieldsToLines(fields []Field) ([]StatLine, error) {
+	lines := make([]StatLine, 0)
+	index := make(map[string]int)
+
+	for _, f := range fields {
+		key := f.objectKey()
+		idx, ok := index[key]
+		if !ok {
+			idx = len(lines)
+			index[key] = idx
+			lines = append(lines, StatLine{})
+		}
+
+		fi, ok := statFieldIndex[f.Name]
+		if !ok {
+			continue
+		}
+
+		if err := setStatField(&lines[idx], fi, f); err != nil {
+			return nil, fmt.Errorf("%s field %s: %w", key, f.Name, err)
+		}
+	}
+
+	return lines, nil
+}
+
+func setStatField(line *StatLine, fi int, f Field) error {
+	return setValue(reflect.ValueOf(line).Elem().Field(fi), f.Type, f.Value)
+}
+
+// setValue sets string or NullInt64 struct field from the reported value
+func setValue(v reflect.Value, typ, value string) error {
+	switch dst := v.Addr().Interface().(type) {
+	case *string:
+		*dst = value
+	case *NullInt64:
+		if typ == "flt" {
+			fv, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return err
+			}
+			*dst = NewInt64(int64(fv))
+			return nil
+		}
+
+		return dst.UnmarshalCSV(value)
+	default:
+		return fmt.Errorf("unsupported field type: %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package haproxy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testingTyped = `F.2.0.0.pxname.1:KNS:str:https
+F.2.0.1.svname.1:KNS:str:FRONTEND
+F.2.0.4.scur.1:MGP:u32:0
+F.2.0.5.smax.1:MMP:u32:23
+F.2.0.6.slim.1:CLP:u32:100000
+F.2.0.17.status.1:SGP:str:OPEN
+F.2.0.32.type.1:CGS:u32:0
+F.2.0.999.some_future_field.1:MGP:u64:42
+
+B.57.0.0.pxname.1:KNS:str:bk_dashboard_cluster
+B.57.0.1.svname.1:KNS:str:BACKEND
+B.57.0.2.qcur.1:MGP:u32:0
+B.57.0.4.scur.1:MGP:u32:3
+B.57.0.6.slim.1:CLP:u32:10000
+B.57.0.17.status.1:SGP:str:UP
+B.57.0.32.type.1:CGS:u32:1
+B.57.0.60.rtime.1:MaP:u32:12
+
+S.57.1.0.pxname.1:KNS:str:bk_dashboard_cluster
+S.57.1.1.svname.1:KNS:str:ctrl01
+S.57.1.4.scur.1:MGP:u32:1
+S.57.1.6.slim.1:CLP:u32:
+S.57.1.17.status.1:SGP:str:UP
+S.57.1.32.type.1:CGS:u32:2
+S.57.1.36.check_status.1:SGP:str:L7OK
+S.57.1.64.check_desc.1:SGP:str:Layer7 check passed: OK
+
+S.57.2.0.pxname.1:KNS:str:bk_dashboard_cluster
+S.57.2.1.svname.1:KNS:str:ctrl02
+S.57.2.4.scur.1:MGP:u32:2
+S.57.2.17.status.1:SGP:str:DOWN
+S.57.2.32.type.1:CGS:u32:2
+S.57.2.36.check_status.1:SGP:str:L4CON
+`
+
+const testingJSON = `[
+  [
+    {"objType":"Frontend","proxyId":2,"id":0,"field":{"pos":0,"name":"pxname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"https"}},
+    {"objType":"Frontend","proxyId":2,"id":0,"field":{"pos":1,"name":"svname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"FRONTEND"}},
+    {"objType":"Frontend","proxyId":2,"id":0,"field":{"pos":4,"name":"scur"},"processNum":1,"tags":{"origin":"Metric","nature":"Gauge","scope":"Process"},"value":{"type":"u32","value":0}},
+    {"objType":"Frontend","proxyId":2,"id":0,"field":{"pos":6,"name":"slim"},"processNum":1,"tags":{"origin":"Config","nature":"Limit","scope":"Process"},"value":{"type":"u32","value":100000}},
+    {"objType":"Frontend","proxyId":2,"id":0,"field":{"pos":17,"name":"status"},"processNum":1,"tags":{"origin":"Status","nature":"Gauge","scope":"Process"},"value":{"type":"str","value":"OPEN"}},
+    {"objType":"Frontend","proxyId":2,"id":0,"field":{"pos":999,"name":"some_future_field"},"processNum":1,"tags":{"origin":"Metric","nature":"Gauge","scope":"Process"},"value":{"type":"flt","value":0.5}}
+  ],
+  [
+    {"objType":"Backend","proxyId":57,"id":0,"field":{"pos":0,"name":"pxname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"bk_dashboard_cluster"}},
+    {"objType":"Backend","proxyId":57,"id":0,"field":{"pos":1,"name":"svname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"BACKEND"}},
+    {"objType":"Backend","proxyId":57,"id":0,"field":{"pos":4,"name":"scur"},"processNum":1,"tags":{"origin":"Metric","nature":"Gauge","scope":"Process"},"value":{"type":"u32","value":3}},
+    {"objType":"Backend","proxyId":57,"id":0,"field":{"pos":6,"name":"slim"},"processNum":1,"tags":{"origin":"Config","nature":"Limit","scope":"Process"},"value":{"type":"u32","value":10000}},
+    {"objType":"Backend","proxyId":57,"id":0,"field":{"pos":17,"name":"status"},"processNum":1,"tags":{"origin":"Status","nature":"Gauge","scope":"Process"},"value":{"type":"str","value":"UP"}},
+    {"objType":"Backend","proxyId":57,"id":0,"field":{"pos":60,"name":"rtime"},"processNum":1,"tags":{"origin":"Metric","nature":"Avg","scope":"Process"},"value":{"type":"u32","value":12}}
+  ],
+  [
+    {"objType":"Server","proxyId":57,"id":1,"field":{"pos":0,"name":"pxname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"bk_dashboard_cluster"}},
+    {"objType":"Server","proxyId":57,"id":1,"field":{"pos":1,"name":"svname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"ctrl01"}},
+    {"objType":"Server","proxyId":57,"id":1,"field":{"pos":4,"name":"scur"},"processNum":1,"tags":{"origin":"Metric","nature":"Gauge","scope":"Process"},"value":{"type":"u32","value":1}},
+    {"objType":"Server","proxyId":57,"id":1,"field":{"pos":17,"name":"status"},"processNum":1,"tags":{"origin":"Status","nature":"Gauge","scope":"Process"},"value":{"type":"str","value":"UP"}},
+    {"objType":"Server","proxyId":57,"id":1,"field":{"pos":36,"name":"check_status"},"processNum":1,"tags":{"origin":"Status","nature":"Output","scope":"Process"},"value":{"type":"str","value":"L7OK"}}
+  ],
+  [
+    {"objType":"Server","proxyId":57,"id":2,"field":{"pos":0,"name":"pxname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"bk_dashboard_cluster"}},
+    {"objType":"Server","proxyId":57,"id":2,"field":{"pos":1,"name":"svname"},"processNum":1,"tags":{"origin":"Key","nature":"Name","scope":"Service"},"value":{"type":"str","value":"ctrl02"}},
+    {"objType":"Server","proxyId":57,"id":2,"field":{"pos":4,"name":"scur"},"processNum":1,"tags":{"origin":"Metric","nature":"Gauge","scope":"Process"},"value":{"type":"u32","value":2}},
+    {"objType":"Server","proxyId":57,"id":2,"field":{"pos":17,"name":"status"},"processNum":1,"tags":{"origin":"Status","nature":"Gauge","scope":"Process"},"value":{"type":"str","value":"DOWN"}},
+    {"objType":"Server","proxyId":57,"id":2,"field":{"pos":36,"name":"check_status"},"processNum":1,"tags":{"origin":"Status","nature":"Output","scope":"Process"},"value":{"type":"str","value":"L4CON"}}
+  ]
+]
+`
+
+func assertTypedStats(t *testing.T, stats Stats) {
+	assert := assert.New(t)
+
+	assert.Len(stats, 2)
+
+	fe := stats["https"][Frontend]
+	assert.Equal("OPEN", fe.Status)
+	assert.Equal(NewInt64(100000), fe.Slim)
+
+	bk := stats["bk_dashboard_cluster"]
+	assert.Len(bk, 3)
+	assert.Len(bk.Servers(), 2)
+	assert.Equal(NewInt64(3), bk[Backend].Scur)
+	assert.Equal(NewInt64(12), bk[Backend].Rtime)
+	assert.Equal("L7OK", bk["ctrl01"].CheckStatus)
+	assert.False(bk["ctrl01"].HasSessionLimit())
+	assert.False(bk["ctrl02"].IsUp(nil))
+}
+
+func TestParseStatTyped(t *testing.T) {
+	assert := assert.New(t)
+
+	stats, _, err := ParseStatTyped(strings.NewReader(testingTyped))
+	assert.NoError(err)
+	assertTypedStats(t, stats)
+	assert.Equal("Layer7 check passed: OK", stats["bk_dashboard_cluster"]["ctrl01"].CheckDesc)
+
+	_, _, err = ParseStatTyped(strings.NewReader("F.2.0.pxname.1:KNS:str:https\n"))
+	assert.Error(err)
+
+	_, _, err = ParseStatTyped(strings.NewReader("F.2.0.4.scur.1:MGP:u32:many\n"))
+	assert.Error(err)
+}
+
+func TestParseTypedField(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := parseTypedField("S.57.1.64.check_desc.1:SGP:str:Layer7 check passed: OK")
+	assert.NoError(err)
+	assert.Equal(Field{
+		ObjType: "S",
+		ProxyID: 57,
+		ID:      1,
+		Pos:     64,
+		Name:    "check_desc",
+		Process: 1,
+		Origin:  "S",
+		Nature:  "G",
+		Scope:   "P",
+		Type:    "str",
+		Value:   "Layer7 check passed: OK",
+	}, f)
+}
+
+func TestParseStatJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	stats, _, err := ParseStatJSON(strings.NewReader(testingJSON))
+	assert.NoError(err)
+	assertTypedStats(t, stats)
+
+	_, _, err = ParseStatJSON(strings.NewReader("Unknown command.\n"))
+	assert.Error(err)
+}
+
+func TestGetStatsFormats(t *testing.T) {
+	assert := assert.New(t)
+
+	socketPath := filepath.Join(t.TempDir(), "haproxy.sock")
+	serveRuntimeAPI(t, "unix", socketPath, map[string]string{
+		"show stat typed": testingTyped,
+		"show stat json":  testingJSON,
+	})
+
+	d := &Dialer{Network: "unix", Address: socketPath}
+
+	for _, format := range []StatFormat{StatFormatTyped, StatFormatJSON} {
+		stats, _, err := GetStats(d, format)
+		assert.NoError(err, format)
+		assertTypedStats(t, stats)
+	}
+}
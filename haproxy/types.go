@@ -0,0 +1,100 @@
+package haproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NullInt64 is a numeric stat value which may be absent.
+// HAProxy leaves a cell empty when the metric does not apply to the object,
+// e.g. slim for servers without maxconn, and that must not be confused with zero.
+type NullInt64 struct {
+	Int64 int64
+	Valid bool
+}
+
+// NewInt64 makes a valid NullInt64
+func NewInt64(v int64) NullInt64 {
+	return NullInt64{Int64: v, Valid: true}
+}
+
+// Get returns the value and if it is present
+func (n NullInt64) Get() (int64, bool) {
+	return n.Int64, n.Valid
+}
+
+// Or returns the value or def if the value is absent
+func (n NullInt64) Or(def int64) int64 {
+	if !n.Valid {
+		return def
+	}
+
+	return n.Int64
+}
+
+// Float32 returns the value as float32, absent value is 0
+func (n NullInt64) Float32() float32 {
+	return float32(n.Int64)
+}
+
+// Float64 returns the value as float64, absent value is 0
+func (n NullInt64) Float64() float64 {
+	return float64(n.Int64)
+}
+
+func (n NullInt64) String() string {
+	if !n.Valid {
+		return ""
+	}
+
+	return strconv.FormatInt(n.Int64, 10)
+}
+
+// UnmarshalCSV implements gocsv.TypeUnmarshaller
+func (n *NullInt64) UnmarshalCSV(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		*n = NullInt64{}
+		return nil
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q: %w", s, err)
+	}
+
+	*n = NewInt64(v)
+	return nil
+}
+
+// MarshalCSV implements gocsv.TypeMarshaller
+func (n NullInt64) MarshalCSV() (string, error) {
+	return n.String(), nil
+}
+
+// MarshalJSON encodes absent value as null
+func (n NullInt64) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(n.Int64)
+}
+
+// UnmarshalJSON decodes number or null
+func (n *NullInt64) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*n = NullInt64{}
+		return nil
+	}
+
+	var v int64
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*n = NewInt64(v)
+	return nil
+}
@@ -0,0 +1,43 @@
+package haproxy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNullInt64(t *testing.T) {
+	assert := assert.New(t)
+
+	var n NullInt64
+	assert.NoError(n.UnmarshalCSV(""))
+	assert.False(n.Valid)
+	assert.Equal(int64(-1), n.Or(-1))
+
+	assert.NoError(n.UnmarshalCSV("0"))
+	assert.Equal(NewInt64(0), n)
+	assert.Equal(int64(0), n.Or(-1))
+
+	assert.NoError(n.UnmarshalCSV("-1"))
+	v, ok := n.Get()
+	assert.True(ok)
+	assert.Equal(int64(-1), v)
+
+	assert.Error(n.UnmarshalCSV("L7OK"))
+
+	b, err := json.Marshal(struct {
+		A NullInt64 `json:"a"`
+		B NullInt64 `json:"b"`
+	}{A: NewInt64(42)})
+	assert.NoError(err)
+	assert.JSONEq(`{"a":42,"b":null}`, string(b))
+
+	var out struct {
+		A NullInt64 `json:"a"`
+		B NullInt64 `json:"b"`
+	}
+	assert.NoError(json.Unmarshal(b, &out))
+	assert.Equal(NewInt64(42), out.A)
+	assert.Equal(NullInt64{}, out.B)
+}
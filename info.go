@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// infoLimit is a process-wide usage compared against its configured limit
+type infoLimit struct {
+	Name     string
+	Cur      haproxy.NullInt64
+	Limit    haproxy.NullInt64
+	Warning  float32
+	Critical float32
+}
+
+// infoChecksEnabled reports if any process-wide threshold is set, so "show info" is needed
+func infoChecksEnabled() bool {
+	for _, v := range []float32{
+		plugin.ConnsWarningPercent, plugin.ConnsCriticalPercent,
+		plugin.SslConnsWarningPercent, plugin.SslConnsCriticalPercent,
+		plugin.RateWarningPercent, plugin.RateCriticalPercent,
+		plugin.SslRateWarningPercent, plugin.SslRateCriticalPercent,
+		plugin.PipesWarningPercent, plugin.PipesCriticalPercent,
+		plugin.IdleWarningPercent, plugin.IdleCriticalPercent,
+	} {
+		if v > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkInfo compares process-wide usage with its limits and records tripped thresholds to the report
+func checkInfo(pr *ProcessReport, info *haproxy.Info) int {
+	limits := []infoLimit{
+		{"Connections", info.CurrConns, info.Maxconn, plugin.ConnsWarningPercent, plugin.ConnsCriticalPercent},
+		{"SSL connections", info.CurrSslConns, info.MaxSslConns, plugin.SslConnsWarningPercent, plugin.SslConnsCriticalPercent},
+		{"Connection rate", info.ConnRate, info.ConnRateLimit, plugin.RateWarningPercent, plugin.RateCriticalPercent},
+		{"Session rate", info.SessRate, info.SessRateLimit, plugin.RateWarningPercent, plugin.RateCriticalPercent},
+		{"SSL rate", info.SslRate, info.SslRateLimit, plugin.SslRateWarningPercent, plugin.SslRateCriticalPercent},
+		{"Pipes", info.PipesUsed, info.Maxpipes, plugin.PipesWarningPercent, plugin.PipesCriticalPercent},
+	}
+
+	ret := sensu.CheckStateOK
+	for _, l := range limits {
+		pct, ok := haproxy.UsagePercentage(l.Cur, l.Limit)
+		if !ok {
+			continue
+		}
+
+		state := thresholdState(pct, l.Warning, l.Critical)
+		if state > sensu.CheckStateOK {
+			reason := fmt.Sprintf("%d of %d (%.0f%%)", l.Cur.Int64, l.Limit.Int64, pct)
+			log.Printf("%s %s: %s", stateName(state), l.Name, reason)
+			pr.trip(l.Name, state, reason)
+		}
+		ret = max(ret, state)
+	}
+
+	// Idle_pct: the lower the busier
+	if idle, ok := info.IdlePct.Get(); ok {
+		state := sensu.CheckStateOK
+		if plugin.IdleCriticalPercent > 0 && float32(idle) < plugin.IdleCriticalPercent {
+			state = sensu.CheckStateCritical
+		} else if plugin.IdleWarningPercent > 0 && float32(idle) < plugin.IdleWarningPercent {
+			state = sensu.CheckStateWarning
+		}
+
+		if state > sensu.CheckStateOK {
+			log.Printf("%s Idle: %d%%", stateName(state), idle)
+			pr.trip("Idle", state, fmt.Sprintf("%d%%", idle))
+		}
+		ret = max(ret, state)
+	}
+
+	pr.setStatus(ret)
+	return ret
+}
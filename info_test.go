@@ -0,0 +1,98 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+const testingInfo = `Name: HAProxy
+Version: 2.4.22
+Maxconn: 100000
+CurrConns: 91000
+MaxSslConns: 0
+CurrSslConns: 4
+ConnRate: 3
+ConnRateLimit: 0
+SessRate: 3
+SessRateLimit: 0
+SslRate: 60
+SslRateLimit: 100
+Maxpipes: 0
+PipesUsed: 0
+Idle_pct: 8
+`
+
+func TestCheckInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	info, _, err := haproxy.ParseInfo(strings.NewReader(testingInfo))
+	assert.NoError(err)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+
+	assert.False(infoChecksEnabled())
+	assert.Equal(sensu.CheckStateOK, checkInfo(&ProcessReport{}, info))
+
+	plugin.ConnsWarningPercent = 80
+	assert.True(infoChecksEnabled())
+	assert.Equal(sensu.CheckStateWarning, checkInfo(&ProcessReport{}, info))
+
+	plugin.ConnsCriticalPercent = 90
+	pr := &ProcessReport{}
+	assert.Equal(sensu.CheckStateCritical, checkInfo(pr, info))
+	assert.Equal("CRITICAL", pr.State)
+	assert.Equal([]Tripped{{Name: "Connections", State: "CRITICAL", Reason: "91000 of 100000 (91%)"}}, pr.Tripped)
+
+	plugin = Config{SslRateWarningPercent: 50, SslRateCriticalPercent: 75}
+	assert.Equal(sensu.CheckStateWarning, checkInfo(&ProcessReport{}, info))
+
+	// unlimited SSL connections are never reported
+	plugin = Config{SslConnsWarningPercent: 1}
+	assert.Equal(sensu.CheckStateOK, checkInfo(&ProcessReport{}, info))
+
+	plugin = Config{IdleWarningPercent: 20, IdleCriticalPercent: 5}
+	assert.Equal(sensu.CheckStateWarning, checkInfo(&ProcessReport{}, info))
+}
+
+func TestRunCheckInfoReport(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	defer func() { instances = nil }()
+
+	path := filepath.Join(t.TempDir(), "haproxy.sock")
+	serveCommands(t, path, map[string]string{
+		"show stat": strings.TrimPrefix(testingCSV, "\n"),
+		"show info": testingInfo,
+	})
+
+	// all services are fine, only the process connections limit trips
+	plugin = Config{
+		Sockets:              []string{path},
+		StatFormat:           string(haproxy.StatFormatCSV),
+		AllServices:          true,
+		ConnsCriticalPercent: 90,
+	}
+
+	_, err := checkArgs(nil)
+	if !assert.NoError(err) {
+		return
+	}
+
+	status, report, err := runCheck(nil)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateCritical, status)
+	for _, pr := range report.Proxies {
+		assert.Equal(sensu.CheckStateOK, pr.Status, pr.Pxname)
+	}
+	if assert.Len(report.Processes, 1) && assert.Len(report.Processes[0].Tripped, 1) {
+		assert.Equal("CRITICAL", report.Processes[0].State)
+		assert.Equal("Connections", report.Processes[0].Tripped[0].Name)
+	}
+}
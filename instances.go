@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"go.uber.org/multierr"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// Instance is one HAProxy process to check, available by runtime API socket or stats page
+type Instance struct {
+	Name   string
+	Dialer *haproxy.Dialer
+	HTTP   *haproxy.HTTPSource
+}
+
+// instanceResult is the data fetched from an Instance
+type instanceResult struct {
+	Instance *Instance
+	Stats    haproxy.Stats
+	RawData  []byte
+	Info     *haproxy.Info
+	RawInfo  []byte
+	Err      error
+	// Keys are names of selected services
+	Keys []string
+	// Proxies are per-proxy outcomes for --proxy-events
+	Proxies []proxyResult
+	// Process is the outcome of process-wide thresholds for --output json
+	Process *ProcessReport
+	// Reports are per-proxy outcomes for --output json
+	Reports []*ProxyReport
+}
+
+// multiInstance reports if the check is configured for several sockets,
+// so results should be tagged with the instance name
+func multiInstance() bool {
+	if plugin.URL != "" {
+		return false
+	}
+
+	return len(plugin.Sockets) > 1 || (len(plugin.Sockets) == 1 && isGlob(plugin.Sockets[0]))
+}
+
+// Tag returns the instance name for metric tags, empty for a single instance check
+func (in *Instance) Tag() string {
+	if !multiInstance() {
+		return ""
+	}
+
+	return in.Name
+}
+
+func (in *Instance) statePrefix() string {
+	if !multiInstance() {
+		return ""
+	}
+
+	return in.Name + "/"
+}
+
+// fetch query stats and, if needed, process info
+func (in *Instance) fetch() *instanceResult {
+	r := &instanceResult{Instance: in}
+	format := haproxy.StatFormat(plugin.StatFormat)
+
+	switch {
+	case in.HTTP != nil:
+		r.Stats, r.RawData, r.Err = haproxy.GetStatsHTTP(in.HTTP, format)
+	case plugin.Master:
+		r.Stats, r.RawData, r.Err = haproxy.GetMasterStats(in.Dialer, format)
+	default:
+		r.Stats, r.RawData, r.Err = haproxy.GetStats(in.Dialer, format)
+	}
+	if r.Err != nil {
+		r.Err = fmt.Errorf("Failed to get service stats%s: %w", in.errorSuffix(), r.Err)
+		return r
+	}
+
+	if infoChecksEnabled() && in.Dialer != nil {
+		if plugin.Master {
+			r.Info, r.RawInfo, r.Err = haproxy.GetMasterInfo(in.Dialer)
+		} else {
+			r.Info, r.RawInfo, r.Err = haproxy.GetInfo(in.Dialer)
+		}
+		if r.Err != nil {
+			r.Err = fmt.Errorf("Failed to get process info%s: %w", in.errorSuffix(), r.Err)
+		}
+	}
+
+	return r
+}
+
+func (in *Instance) errorSuffix() string {
+	if !multiInstance() {
+		return ""
+	}
+
+	return " of " + in.Name
+}
+
+// executor returns the runtime API of the instance, the current worker with --master
+func (in *Instance) executor() (haproxy.Executor, error) {
+	if plugin.Master {
+		return haproxy.CurrentWorker(in.Dialer)
+	}
+
+	return in.Dialer, nil
+}
+
+// forInstances runs f for all instances, at most --concurrency at once.
+// Results are in the instances order.
+func forInstances[T any](instances []*Instance, f func(in *Instance) T) []T {
+	results := make([]T, len(instances))
+	sem := make(chan struct{}, max(plugin.Concurrency, 1))
+
+	var wg sync.WaitGroup
+	for i, in := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = f(in)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// fetchInstances query stats of all instances
+func fetchInstances(instances []*Instance) []*instanceResult {
+	return forInstances(instances, (*Instance).fetch)
+}
+
+// modeResult is the data fetched from an Instance by a check mode
+type modeResult[T any] struct {
+	Executor haproxy.Executor
+	Data     T
+	RawData  []byte
+	Err      error
+}
+
+// checkInstances runs a check mode: data of all instances is fetched concurrently,
+// then checked in the instances order, so their logs are not mixed.
+// what names the data in errors and debug output.
+func checkInstances[T any](
+	what string,
+	fetch func(in *Instance, e haproxy.Executor) (T, []byte, error),
+	check func(in *Instance, e haproxy.Executor, data T) (int, error),
+) (int, error) {
+	results := forInstances(instances, func(in *Instance) modeResult[T] {
+		var r modeResult[T]
+		r.Executor, r.Err = in.executor()
+		if r.Err == nil {
+			r.Data, r.RawData, r.Err = fetch(in, r.Executor)
+		}
+		return r
+	})
+
+	ret := sensu.CheckStateOK
+	var err error
+	for i, r := range results {
+		in := instances[i]
+		if r.Err != nil {
+			ret = worstState(ret, sensu.CheckStateUnknown)
+			err = multierr.Append(err, fmt.Errorf("Failed to get %s%s: %w", what, in.errorSuffix(), r.Err))
+			continue
+		}
+
+		if multiInstance() {
+			log.Printf("Instance %s:", in.Name)
+		}
+
+		newret, err2 := check(in, r.Executor, r.Data)
+		ret = worstState(ret, newret)
+		err = multierr.Append(err, err2)
+
+		if plugin.Debug && newret > sensu.CheckStateOK {
+			log.Printf("Raw %s data\n---\n%s", what, string(r.RawData))
+		}
+	}
+
+	return ret, err
+}
+
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// socketInstances parses --socket addresses and expands globs of socket paths
+func socketInstances(addresses []string) ([]*Instance, error) {
+	dialers := make([]*haproxy.Dialer, 0, len(addresses))
+	for _, address := range addresses {
+		d, err := haproxy.ParseAddress(address)
+		if err != nil {
+			return nil, err
+		}
+
+		if !d.IsUnix() {
+			dialers = append(dialers, d)
+			continue
+		}
+
+		paths := []string{d.Address}
+		if isGlob(d.Address) {
+			paths, err = filepath.Glob(d.Address)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", address, err)
+			} else if len(paths) == 0 {
+				return nil, fmt.Errorf("%s: no sockets found", address)
+			}
+		}
+
+		for _, path := range paths {
+			fi, err := os.Lstat(path)
+			if err != nil {
+				return nil, err
+			} else if fi.Mode()&os.ModeSocket == 0 {
+				return nil, fmt.Errorf("%s is not socket: %v", path, fi.Mode())
+			}
+
+			dialers = append(dialers, &haproxy.Dialer{Network: d.Network, Address: path, Timeout: d.Timeout})
+		}
+	}
+
+	// socket file name without extension, or host:port
+	names := make(map[string]int)
+	ret := make([]*Instance, 0, len(dialers))
+	for _, d := range dialers {
+		name := d.Address
+		if d.IsUnix() {
+			name = strings.TrimSuffix(filepath.Base(d.Address), filepath.Ext(d.Address))
+		}
+		names[name]++
+		ret = append(ret, &Instance{Name: name, Dialer: d})
+	}
+
+	for _, in := range ret {
+		if names[in.Name] > 1 {
+			in.Name = in.Dialer.String()
+		}
+	}
+
+	return ret, nil
+}
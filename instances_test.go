@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// serveStats starts fake runtime API on the unix socket which answers show stat
+func serveStats(t *testing.T, path, csv string) {
+	t.Helper()
+	serveCommands(t, path, map[string]string{"show stat": csv})
+}
+
+// serveCommands starts fake runtime API on the UNIX socket which answers commands from responses map
+func serveCommands(t *testing.T, path string, responses map[string]string) {
+	t.Helper()
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer c.Close()
+
+				cmd, _ := bufio.NewReader(c).ReadString('\n')
+				if resp, ok := responses[strings.TrimSpace(cmd)]; ok {
+					_, _ = c.Write([]byte(resp))
+				}
+			}()
+		}
+	}()
+}
+
+func TestSocketInstances(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "haproxy-check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	serveStats(t, filepath.Join(dir, "edge.sock"), testingCSV)
+	serveStats(t, filepath.Join(dir, "mesh.sock"), testingCSV)
+
+	in, err := socketInstances([]string{filepath.Join(dir, "*.sock"), "tcp://127.0.0.1:9999"})
+	if !assert.NoError(err) || !assert.Len(in, 3) {
+		return
+	}
+	assert.Equal("edge", in[0].Name)
+	assert.Equal("mesh", in[1].Name)
+	assert.Equal("127.0.0.1:9999", in[2].Name)
+
+	_, err = socketInstances([]string{filepath.Join(dir, "*.socket")})
+	assert.Error(err)
+
+	_, err = socketInstances([]string{filepath.Join(dir, "missing.sock")})
+	assert.Error(err)
+}
+
+func TestExecuteCheckMultiSocket(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	defer func() { instances = nil }()
+
+	dir, err := os.MkdirTemp("", "haproxy-check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	csv := strings.TrimPrefix(testingCSV, "\n")
+	serveStats(t, filepath.Join(dir, "edge.sock"), csv)
+	serveStats(t, filepath.Join(dir, "mesh.sock"), strings.ReplaceAll(csv, ",UP,1,1,", ",DOWN,1,1,"))
+
+	plugin = Config{
+		Sockets:     []string{filepath.Join(dir, "*.sock")},
+		Service:     "ipmi_exporter",
+		Concurrency: 1,
+		Thresholds:  Thresholds{WarningPercent: 50, CriticalPercent: 25},
+	}
+
+	status, err := checkArgs(nil)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+	assert.Len(instances, 2)
+	assert.True(multiInstance())
+
+	status, err = executeCheck(nil)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateCritical, status)
+
+	// healthy instance only
+	plugin.Sockets = []string{filepath.Join(dir, "edge.sock")}
+	_, err = checkArgs(nil)
+	assert.NoError(err)
+	assert.False(multiInstance())
+
+	status, err = executeCheck(nil)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+
+	// unreachable instance is unknown, the others are still checked
+	plugin.Sockets = []string{filepath.Join(dir, "*.sock")}
+	_, err = checkArgs(nil)
+	assert.NoError(err)
+	instances[1].Dialer.Address = filepath.Join(dir, "gone.sock")
+
+	status, err = executeCheck(nil)
+	assert.Error(err)
+	assert.Equal(sensu.CheckStateUnknown, status)
+
+	// critical instance outranks the unreachable one
+	_, err = checkArgs(nil)
+	assert.NoError(err)
+	instances[0].Dialer.Address = filepath.Join(dir, "gone.sock")
+
+	status, err = executeCheck(nil)
+	assert.Error(err)
+	assert.Equal(sensu.CheckStateCritical, status)
+}
+
+func TestExecuteCheckModes(t *testing.T) {
+	table := func(used int) map[string]string {
+		return map[string]string{
+			"show table": fmt.Sprintf("# table: front_pub, type: ip, size:100, used:%d\n", used),
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		responses []map[string]string
+		config    Config
+		argsErr   bool
+		expected  int
+	}{
+		{
+			name: "ssl-cert",
+			responses: []map[string]string{{
+				"show ssl cert":          "# filename\nsite.pem\n",
+				"show ssl cert site.pem": "Filename: site.pem\nnotAfter: Sep 14 12:00:00 2021 GMT\nSubject: /CN=example.com\n",
+			}},
+			config:   Config{Mode: ModeSSLCert, SSLCertWarningDays: 30, SSLCertCriticalDays: 7},
+			expected: sensu.CheckStateCritical,
+		},
+		{
+			name:      "table",
+			responses: []map[string]string{table(97)},
+			config:    Config{Mode: ModeTable, TableWarningPercent: 80, TableCriticalPercent: 95},
+			expected:  sensu.CheckStateCritical,
+		},
+		{
+			name:      "table on concurrent sockets",
+			responses: []map[string]string{table(10), table(85), table(20)},
+			config:    Config{Mode: ModeTable, Concurrency: 2, TableWarningPercent: 80, TableCriticalPercent: 95},
+			expected:  sensu.CheckStateWarning,
+		},
+		{
+			name:      "table top without counter",
+			responses: []map[string]string{table(10)},
+			config:    Config{Mode: ModeTable, TableTop: 5},
+			argsErr:   true,
+		},
+		{
+			name: "peers",
+			responses: []map[string]string{{
+				"show peers": "0x1: [15/Apr/2019:11:28:01] id=mypeers state=0 flags=0x3 resync_timeout=<PAST>\n" +
+					"  0x2: id=hap2(remote) addr=10.0.0.2:10000 status=CONN reconnect=3s confirm=0\n",
+			}},
+			config:   Config{Mode: ModePeers, PeerStatusSeverity: SeverityCritical},
+			expected: sensu.CheckStateCritical,
+		},
+		{
+			name: "resolvers",
+			responses: []map[string]string{{
+				"show resolvers": "Resolvers section mydns\n nameserver dns1:\n  sent: 8\n  valid: 8\n",
+				"show stat": "# pxname,svname,status,\n" +
+					"be_api,api1,MAINT (resolution),\n" +
+					"be_api,BACKEND,DOWN,\n",
+			}},
+			config: Config{
+				Mode:               ModeResolvers,
+				StatFormat:         string(haproxy.StatFormatCSV),
+				ResolutionSeverity: SeverityWarning,
+			},
+			expected: sensu.CheckStateWarning,
+		},
+	}
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	defer func() { instances = nil }()
+	defer func(saved *StateStore) { state = saved }(state)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			dir := t.TempDir()
+			for i, responses := range tc.responses {
+				serveCommands(t, filepath.Join(dir, fmt.Sprintf("haproxy%d.sock", i)), responses)
+			}
+
+			plugin = tc.config
+			plugin.Sockets = []string{filepath.Join(dir, "*.sock")}
+			plugin.StateDir = t.TempDir()
+
+			_, err := checkArgs(nil)
+			if tc.argsErr {
+				assert.Error(err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Len(instances, len(tc.responses))
+
+			status, err := executeCheck(nil)
+			assert.NoError(err)
+			assert.Equal(tc.expected, status)
+		})
+	}
+}
@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	corev2 "github.com/sensu/core/v2"
 	"github.com/sensu/sensu-plugin-sdk/sensu"
@@ -16,23 +17,71 @@ import (
 	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
 )
 
+// Check modes
+const (
+	ModeStats     = "stats"
+	ModeSSLCert   = "ssl-cert"
+	ModeTable     = "table"
+	ModePeers     = "peers"
+	ModeResolvers = "resolvers"
+)
+
 // Config represents the check plugin config.
 type Config struct {
 	sensu.PluginConfig
-	SocketPath             string
-	AllServices            bool
-	Service                string
-	MissingOk              bool
-	MissingFail            bool
-	WarningPercent         float32
-	CriticalPercent        float32
-	SessionWarningPercent  float32
-	SessionCriticalPercent float32
-	// BackendSessionWarningPercent  float32
-	// BackendSessionCriticalPercent float32
-	MinWarningCount  int
-	MinCriticalCount int
-	Debug            bool
+	Thresholds
+	Sockets                  []string
+	URL                      string
+	Username                 string
+	Password                 string
+	CAFile                   string
+	CertFile                 string
+	KeyFile                  string
+	InsecureSkipVerify       bool
+	Master                   bool
+	Concurrency              int
+	StatFormat               string
+	Mode                     string
+	AllServices              bool
+	Service                  string
+	IncludeProxy             []string
+	ExcludeProxy             []string
+	IncludeServer            []string
+	ExcludeServer            []string
+	Rules                    string
+	SSLCertWarningDays       int
+	SSLCertCriticalDays      int
+	TableWarningPercent      float32
+	TableCriticalPercent     float32
+	TableTop                 int
+	TableTopCounter          string
+	PeerStatusSeverity       string
+	PeerResyncSeverity       string
+	ResolversWarningPercent  float32
+	ResolversCriticalPercent float32
+	ResolversMinSent         int
+	ResolutionSeverity       string
+	ResolutionMinResolved    int
+	ConnsWarningPercent      float32
+	ConnsCriticalPercent     float32
+	SslConnsWarningPercent   float32
+	SslConnsCriticalPercent  float32
+	RateWarningPercent       float32
+	RateCriticalPercent      float32
+	SslRateWarningPercent    float32
+	SslRateCriticalPercent   float32
+	PipesWarningPercent      float32
+	PipesCriticalPercent     float32
+	IdleWarningPercent       float32
+	IdleCriticalPercent      float32
+	StateDir                 string
+	MetricsFormat            string
+	EventMetrics             bool
+	ProxyEvents              bool
+	ProxyEntity              string
+	AgentAPIURL              string
+	Output                   string
+	Debug                    bool
 }
 
 var (
@@ -44,15 +93,110 @@ var (
 		},
 	}
 
+	instances []*Instance
+	state     *StateStore
+
 	options = []sensu.ConfigOption{
-		&sensu.PluginConfigOption[string]{
+		&sensu.SlicePluginConfigOption[string]{
 			Path:      "socket",
 			Env:       "HAPROXY_SOCKET",
 			Argument:  "socket",
 			Shorthand: "S",
-			Default:   "/var/run/haproxy.sock",
-			Usage:     "Path to haproxy control socket",
-			Value:     &plugin.SocketPath,
+			Default:   []string{"/var/run/haproxy.sock"},
+			Usage:     "HAProxy runtime API socket: path, unix:///path, tcp://host:port, ipv4@host:port or ipv6@host:port; can be repeated, paths may be globs",
+			Value:     &plugin.Sockets,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:      "url",
+			Env:       "HAPROXY_URL",
+			Argument:  "url",
+			Shorthand: "u",
+			Default:   "",
+			Usage:     "HAProxy stats page URL, e.g. http://127.0.0.1:8404/haproxy?stats (used instead of --socket)",
+			Value:     &plugin.URL,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "username",
+			Env:      "HAPROXY_USERNAME",
+			Argument: "username",
+			Default:  "",
+			Usage:    "Stats page basic auth username",
+			Value:    &plugin.Username,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "password",
+			Env:      "HAPROXY_PASSWORD",
+			Argument: "password",
+			Default:  "",
+			Secret:   true,
+			Usage:    "Stats page basic auth password",
+			Value:    &plugin.Password,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "ca_file",
+			Env:      "HAPROXY_CA_FILE",
+			Argument: "ca-file",
+			Default:  "",
+			Usage:    "Stats page CA certificate file",
+			Value:    &plugin.CAFile,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "cert_file",
+			Env:      "HAPROXY_CERT_FILE",
+			Argument: "cert-file",
+			Default:  "",
+			Usage:    "Stats page client certificate file",
+			Value:    &plugin.CertFile,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "key_file",
+			Env:      "HAPROXY_KEY_FILE",
+			Argument: "key-file",
+			Default:  "",
+			Usage:    "Stats page client key file",
+			Value:    &plugin.KeyFile,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     "insecure_skip_verify",
+			Env:      "HAPROXY_INSECURE_SKIP_VERIFY",
+			Argument: "insecure-skip-verify",
+			Default:  false,
+			Usage:    "Do not verify stats page TLS certificate",
+			Value:    &plugin.InsecureSkipVerify,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     "master",
+			Env:      "HAPROXY_MASTER",
+			Argument: "master",
+			Default:  false,
+			Usage:    "--socket is the master CLI: query all workers listed by show proc, including old ones, and aggregate their stats",
+			Value:    &plugin.Master,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "concurrency",
+			Env:      "HAPROXY_CONCURRENCY",
+			Argument: "concurrency",
+			Default:  4,
+			Usage:    "Maximum number of sockets queried at once",
+			Value:    &plugin.Concurrency,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "stat_format",
+			Env:      "HAPROXY_STAT_FORMAT",
+			Argument: "stat-format",
+			Default:  string(haproxy.StatFormatCSV),
+			Allow:    []string{string(haproxy.StatFormatCSV), string(haproxy.StatFormatTyped), string(haproxy.StatFormatJSON)},
+			Usage:    "show stat output format: csv, typed or json (typed and json do not depend on HAProxy version column set)",
+			Value:    &plugin.StatFormat,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "mode",
+			Env:      "HAPROXY_MODE",
+			Argument: "mode",
+			Default:  ModeStats,
+			Allow:    []string{ModeStats, ModeSSLCert, ModeTable, ModePeers, ModeResolvers},
+			Usage:    "Check mode: stats checks services, ssl-cert checks expiry of loaded SSL certificates, table checks stick table usage, peers checks peers synchronization, resolvers checks DNS nameservers errors and servers in MAINT (resolution)",
+			Value:    &plugin.Mode,
 		},
 		&sensu.PluginConfigOption[string]{
 			Path:      "service",
@@ -90,6 +234,50 @@ var (
 			Usage:     "Service missing is Fail",
 			Value:     &plugin.MissingFail,
 		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:                "include_proxy",
+			Env:                 "HAPROXY_INCLUDE_PROXY",
+			Argument:            "include-proxy",
+			Default:             []string{},
+			UseCobraStringArray: true,
+			Usage:               "Check proxies matching the regex, can be repeated",
+			Value:               &plugin.IncludeProxy,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:                "exclude_proxy",
+			Env:                 "HAPROXY_EXCLUDE_PROXY",
+			Argument:            "exclude-proxy",
+			Default:             []string{},
+			UseCobraStringArray: true,
+			Usage:               "Skip proxies matching the regex, can be repeated",
+			Value:               &plugin.ExcludeProxy,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:                "include_server",
+			Env:                 "HAPROXY_INCLUDE_SERVER",
+			Argument:            "include-server",
+			Default:             []string{},
+			UseCobraStringArray: true,
+			Usage:               "Check only servers matching the regex, can be repeated",
+			Value:               &plugin.IncludeServer,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:                "exclude_server",
+			Env:                 "HAPROXY_EXCLUDE_SERVER",
+			Argument:            "exclude-server",
+			Default:             []string{},
+			UseCobraStringArray: true,
+			Usage:               "Skip servers matching the regex, can be repeated",
+			Value:               &plugin.ExcludeServer,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "rules",
+			Env:      "HAPROXY_RULES",
+			Argument: "rules",
+			Default:  "",
+			Usage:    "YAML or JSON file with per-service threshold overrides by proxy name or pattern",
+			Value:    &plugin.Rules,
+		},
 		&sensu.PluginConfigOption[float32]{
 			Path:      "warning_percent",
 			Env:       "HAPROXY_WARNING_PERCENT",
@@ -126,24 +314,136 @@ var (
 			Usage:     "Session Limit Critical percent",
 			Value:     &plugin.SessionCriticalPercent,
 		},
-		// {
-		// 	Path:      "backend_session_warning_percent",
-		// 	Env:       "HAPROXY_BACKEND_SESSION_WARNING_PERCENT",
-		// 	Argument:  "backend-session-warning-percent",
-		// 	Shorthand: "b",
-		// 	Default:   0,
-		// 	Usage:     "Per Backend Session Limit Warning percent",
-		// 	Value:     &plugin.BackendSessionWarningPercent,
-		// },
-		// {
-		// 	Path:      "backend_session_critical_percent",
-		// 	Env:       "HAPROXY_BACKEND_SESSION_CRITICAL_PERCENT",
-		// 	Argument:  "backend-session-critical-percent",
-		// 	Shorthand: "B",
-		// 	Default:   0,
-		// 	Usage:     "Per Backend Session Limit Critical percent",
-		// 	Value:     &plugin.BackendSessionCriticalPercent,
-		// },
+		&sensu.PluginConfigOption[float32]{
+			Path:     "frontend_session_warning_percent",
+			Env:      "HAPROXY_FRONTEND_SESSION_WARNING_PERCENT",
+			Argument: "frontend-session-warning-percent",
+			Default:  float32(75.0),
+			Usage:    "Frontend and listener Session Limit Warning percent, 0 to disable",
+			Value:    &plugin.FrontendSessionWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "frontend_session_critical_percent",
+			Env:      "HAPROXY_FRONTEND_SESSION_CRITICAL_PERCENT",
+			Argument: "frontend-session-critical-percent",
+			Default:  float32(90.0),
+			Usage:    "Frontend and listener Session Limit Critical percent, 0 to disable",
+			Value:    &plugin.FrontendSessionCriticalPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:      "backend_session_warning_percent",
+			Env:       "HAPROXY_BACKEND_SESSION_WARNING_PERCENT",
+			Argument:  "backend-session-warning-percent",
+			Shorthand: "b",
+			Default:   float32(0),
+			Usage:     "Per Backend Session Limit Warning percent, 0 to disable",
+			Value:     &plugin.BackendSessionWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:      "backend_session_critical_percent",
+			Env:       "HAPROXY_BACKEND_SESSION_CRITICAL_PERCENT",
+			Argument:  "backend-session-critical-percent",
+			Shorthand: "B",
+			Default:   float32(0),
+			Usage:     "Per Backend Session Limit Critical percent, 0 to disable",
+			Value:     &plugin.BackendSessionCriticalPercent,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "queue_warning",
+			Env:      "HAPROXY_QUEUE_WARNING",
+			Argument: "queue-warning",
+			Default:  0,
+			Usage:    "Backend and server current queue length (qcur) Warning, 0 to disable",
+			Value:    &plugin.QueueWarning,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "queue_critical",
+			Env:      "HAPROXY_QUEUE_CRITICAL",
+			Argument: "queue-critical",
+			Default:  0,
+			Usage:    "Backend and server current queue length (qcur) Critical, 0 to disable",
+			Value:    &plugin.QueueCritical,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "queue_warning_percent",
+			Env:      "HAPROXY_QUEUE_WARNING_PERCENT",
+			Argument: "queue-warning-percent",
+			Default:  float32(0),
+			Usage:    "Server queue length of qlimit (maxqueue) Warning percent, 0 to disable",
+			Value:    &plugin.QueueWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "queue_critical_percent",
+			Env:      "HAPROXY_QUEUE_CRITICAL_PERCENT",
+			Argument: "queue-critical-percent",
+			Default:  float32(0),
+			Usage:    "Server queue length of qlimit (maxqueue) Critical percent, 0 to disable",
+			Value:    &plugin.QueueCriticalPercent,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "queue_time_warning",
+			Env:      "HAPROXY_QUEUE_TIME_WARNING",
+			Argument: "queue-time-warning",
+			Default:  0,
+			Usage:    "Backend and server average queue time (qtime) Warning, ms, 0 to disable",
+			Value:    &plugin.QueueTimeWarning,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "queue_time_critical",
+			Env:      "HAPROXY_QUEUE_TIME_CRITICAL",
+			Argument: "queue-time-critical",
+			Default:  0,
+			Usage:    "Backend and server average queue time (qtime) Critical, ms, 0 to disable",
+			Value:    &plugin.QueueTimeCritical,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "connect_time_warning",
+			Env:      "HAPROXY_CONNECT_TIME_WARNING",
+			Argument: "connect-time-warning",
+			Default:  0,
+			Usage:    "Backend and server average connect time (ctime) Warning, ms, 0 to disable",
+			Value:    &plugin.ConnectTimeWarning,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "connect_time_critical",
+			Env:      "HAPROXY_CONNECT_TIME_CRITICAL",
+			Argument: "connect-time-critical",
+			Default:  0,
+			Usage:    "Backend and server average connect time (ctime) Critical, ms, 0 to disable",
+			Value:    &plugin.ConnectTimeCritical,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "response_time_warning",
+			Env:      "HAPROXY_RESPONSE_TIME_WARNING",
+			Argument: "response-time-warning",
+			Default:  0,
+			Usage:    "Backend and server average response time (rtime) Warning, ms, 0 to disable",
+			Value:    &plugin.ResponseTimeWarning,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "response_time_critical",
+			Env:      "HAPROXY_RESPONSE_TIME_CRITICAL",
+			Argument: "response-time-critical",
+			Default:  0,
+			Usage:    "Backend and server average response time (rtime) Critical, ms, 0 to disable",
+			Value:    &plugin.ResponseTimeCritical,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "total_time_warning",
+			Env:      "HAPROXY_TOTAL_TIME_WARNING",
+			Argument: "total-time-warning",
+			Default:  0,
+			Usage:    "Backend and server average total session time (ttime) Warning, ms, 0 to disable",
+			Value:    &plugin.TotalTimeWarning,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "total_time_critical",
+			Env:      "HAPROXY_TOTAL_TIME_CRITICAL",
+			Argument: "total-time-critical",
+			Default:  0,
+			Usage:    "Backend and server average total session time (ttime) Critical, ms, 0 to disable",
+			Value:    &plugin.TotalTimeCritical,
+		},
 		&sensu.PluginConfigOption[int]{
 			Path:      "min_warning_count",
 			Env:       "HAPROXY_MIN_WARNING_COUNT",
@@ -162,6 +462,417 @@ var (
 			Usage:     "Minimum server Critical count",
 			Value:     &plugin.MinCriticalCount,
 		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "ssl_cert_warning_days",
+			Env:      "HAPROXY_SSL_CERT_WARNING_DAYS",
+			Argument: "ssl-cert-warning-days",
+			Default:  30,
+			Usage:    "--mode ssl-cert: Warning when a certificate expires within this many days, 0 to disable",
+			Value:    &plugin.SSLCertWarningDays,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "ssl_cert_critical_days",
+			Env:      "HAPROXY_SSL_CERT_CRITICAL_DAYS",
+			Argument: "ssl-cert-critical-days",
+			Default:  7,
+			Usage:    "--mode ssl-cert: Critical when a certificate expires within this many days, 0 to disable",
+			Value:    &plugin.SSLCertCriticalDays,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "table_warning_percent",
+			Env:      "HAPROXY_TABLE_WARNING_PERCENT",
+			Argument: "table-warning-percent",
+			Default:  float32(80),
+			Usage:    "--mode table: Warning when used entries of a stick table are over this percent of its size, 0 to disable",
+			Value:    &plugin.TableWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "table_critical_percent",
+			Env:      "HAPROXY_TABLE_CRITICAL_PERCENT",
+			Argument: "table-critical-percent",
+			Default:  float32(95),
+			Usage:    "--mode table: Critical when used entries of a stick table are over this percent of its size, 0 to disable",
+			Value:    &plugin.TableCriticalPercent,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "table_top",
+			Env:      "HAPROXY_TABLE_TOP",
+			Argument: "table-top",
+			Default:  0,
+			Usage:    "--mode table: Show this many top entries of tables over thresholds, 0 to disable. Entries with --table-top-counter over 0 are dumped by HAProxy on every run while the table is over thresholds, which is heavy for large tables",
+			Value:    &plugin.TableTop,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "table_top_counter",
+			Env:      "HAPROXY_TABLE_TOP_COUNTER",
+			Argument: "table-top-counter",
+			Default:  "",
+			Usage:    "--mode table: Stored data type to rank top entries by, e.g. http_req_rate",
+			Value:    &plugin.TableTopCounter,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "peer_status_severity",
+			Env:      "HAPROXY_PEER_STATUS_SEVERITY",
+			Argument: "peer-status-severity",
+			Default:  SeverityCritical,
+			Allow:    []string{SeverityWarning, SeverityCritical},
+			Usage:    "--mode peers: Check state when a remote peer is not connected (ESTA): warning or critical",
+			Value:    &plugin.PeerStatusSeverity,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "peer_resync_severity",
+			Env:      "HAPROXY_PEER_RESYNC_SEVERITY",
+			Argument: "peer-resync-severity",
+			Default:  SeverityWarning,
+			Allow:    []string{SeverityWarning, SeverityCritical},
+			Usage:    "--mode peers: Check state when initial resynchronization of stick tables has not finished: warning or critical",
+			Value:    &plugin.PeerResyncSeverity,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "resolvers_warning_percent",
+			Env:      "HAPROXY_RESOLVERS_WARNING_PERCENT",
+			Argument: "resolvers-warning-percent",
+			Default:  float32(10),
+			Usage:    "--mode resolvers: Warning if errors of nameserver queries since the previous run are over the percentage (0 to disable)",
+			Value:    &plugin.ResolversWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "resolvers_critical_percent",
+			Env:      "HAPROXY_RESOLVERS_CRITICAL_PERCENT",
+			Argument: "resolvers-critical-percent",
+			Default:  float32(50),
+			Usage:    "--mode resolvers: Critical if errors of nameserver queries since the previous run are over the percentage (0 to disable)",
+			Value:    &plugin.ResolversCriticalPercent,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "resolvers_min_sent",
+			Env:      "HAPROXY_RESOLVERS_MIN_SENT",
+			Argument: "resolvers-min-sent",
+			Default:  10,
+			Usage:    "--mode resolvers: Minimum queries sent to the nameserver since the previous run to check errors",
+			Value:    &plugin.ResolversMinSent,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "resolution_severity",
+			Env:      "HAPROXY_RESOLUTION_SEVERITY",
+			Argument: "resolution-severity",
+			Default:  SeverityCritical,
+			Allow:    []string{SeverityWarning, SeverityCritical},
+			Usage:    "--mode resolvers: Check state when all servers of a backend are in MAINT (resolution): warning or critical",
+			Value:    &plugin.ResolutionSeverity,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "resolution_min_resolved",
+			Env:      "HAPROXY_RESOLUTION_MIN_RESOLVED",
+			Argument: "resolution-min-resolved",
+			Default:  0,
+			Usage:    "--mode resolvers: Backends with fewer resolved servers are also reported with --resolution-severity (0 to report only backends without any)",
+			Value:    &plugin.ResolutionMinResolved,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "flap_count",
+			Env:      "HAPROXY_FLAP_COUNT",
+			Argument: "flap-count",
+			Default:  0,
+			Usage:    "Server is flapping when it went DOWN this many times since the previous run (chkdown), 0 to disable",
+			Value:    &plugin.FlapCount,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "flap_fail_count",
+			Env:      "HAPROXY_FLAP_FAIL_COUNT",
+			Argument: "flap-fail-count",
+			Default:  0,
+			Usage:    "Server is flapping when this many health checks failed since the previous run (chkfail), 0 to disable",
+			Value:    &plugin.FlapFailCount,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "flap_window",
+			Env:      "HAPROXY_FLAP_WINDOW",
+			Argument: "flap-window",
+			Default:  0,
+			Usage:    "Server is flapping when its status changed within this many seconds (lastchg), 0 to disable",
+			Value:    &plugin.FlapWindow,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "flap_severity",
+			Env:      "HAPROXY_FLAP_SEVERITY",
+			Argument: "flap-severity",
+			Default:  SeverityWarning,
+			Allow:    []string{SeverityWarning, SeverityCritical},
+			Usage:    "Check state for flapping servers: warning or critical",
+			Value:    &plugin.FlapSeverity,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "maint_policy",
+			Env:      "HAPROXY_MAINT_POLICY",
+			Argument: "maint-policy",
+			Default:  PolicyDown,
+			Allow:    []string{PolicyUp, PolicyDown, PolicyIgnore},
+			Usage:    "How MAINT servers count toward up percent and min counts: up, down or ignore",
+			Value:    &plugin.MaintPolicy,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "drain_policy",
+			Env:      "HAPROXY_DRAIN_POLICY",
+			Argument: "drain-policy",
+			Default:  PolicyUp,
+			Allow:    []string{PolicyUp, PolicyDown, PolicyIgnore},
+			Usage:    "How DRAIN servers count toward up percent and min counts: up, down or ignore",
+			Value:    &plugin.DrainPolicy,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "nolb_policy",
+			Env:      "HAPROXY_NOLB_POLICY",
+			Argument: "nolb-policy",
+			Default:  PolicyDown,
+			Allow:    []string{PolicyUp, PolicyDown, PolicyIgnore},
+			Usage:    "How NOLB servers count toward up percent and min counts: up, down or ignore",
+			Value:    &plugin.NolbPolicy,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "transitional_policy",
+			Env:      "HAPROXY_TRANSITIONAL_POLICY",
+			Argument: "transitional-policy",
+			Default:  PolicyDown,
+			Allow:    []string{PolicyState, PolicyUp, PolicyDown, PolicyIgnore},
+			Usage:    "How \"UP n/m\" and \"DOWN n/m\" servers count toward up percent and min counts: state (as UP or DOWN), up, down or ignore",
+			Value:    &plugin.TransitionalPolicy,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "backup_policy",
+			Env:      "HAPROXY_BACKUP_POLICY",
+			Argument: "backup-policy",
+			Default:  PolicyCount,
+			Allow:    []string{PolicyCount, PolicyIgnore},
+			Usage:    "How backup servers count toward up percent and min counts: count by their state or ignore",
+			Value:    &plugin.BackupPolicy,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "conns_warning_percent",
+			Env:      "HAPROXY_CONNS_WARNING_PERCENT",
+			Argument: "conns-warning-percent",
+			Default:  float32(0),
+			Usage:    "Process connections (CurrConns of Maxconn) Warning percent, 0 to disable",
+			Value:    &plugin.ConnsWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "conns_critical_percent",
+			Env:      "HAPROXY_CONNS_CRITICAL_PERCENT",
+			Argument: "conns-critical-percent",
+			Default:  float32(0),
+			Usage:    "Process connections (CurrConns of Maxconn) Critical percent, 0 to disable",
+			Value:    &plugin.ConnsCriticalPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "ssl_conns_warning_percent",
+			Env:      "HAPROXY_SSL_CONNS_WARNING_PERCENT",
+			Argument: "ssl-conns-warning-percent",
+			Default:  float32(0),
+			Usage:    "Process SSL connections (CurrSslConns of MaxSslConns) Warning percent, 0 to disable",
+			Value:    &plugin.SslConnsWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "ssl_conns_critical_percent",
+			Env:      "HAPROXY_SSL_CONNS_CRITICAL_PERCENT",
+			Argument: "ssl-conns-critical-percent",
+			Default:  float32(0),
+			Usage:    "Process SSL connections (CurrSslConns of MaxSslConns) Critical percent, 0 to disable",
+			Value:    &plugin.SslConnsCriticalPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "rate_warning_percent",
+			Env:      "HAPROXY_RATE_WARNING_PERCENT",
+			Argument: "rate-warning-percent",
+			Default:  float32(0),
+			Usage:    "Process connection and session rate (ConnRate of ConnRateLimit, SessRate of SessRateLimit) Warning percent, 0 to disable",
+			Value:    &plugin.RateWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "rate_critical_percent",
+			Env:      "HAPROXY_RATE_CRITICAL_PERCENT",
+			Argument: "rate-critical-percent",
+			Default:  float32(0),
+			Usage:    "Process connection and session rate (ConnRate of ConnRateLimit, SessRate of SessRateLimit) Critical percent, 0 to disable",
+			Value:    &plugin.RateCriticalPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "ssl_rate_warning_percent",
+			Env:      "HAPROXY_SSL_RATE_WARNING_PERCENT",
+			Argument: "ssl-rate-warning-percent",
+			Default:  float32(0),
+			Usage:    "Process SSL session rate (SslRate of SslRateLimit) Warning percent, 0 to disable",
+			Value:    &plugin.SslRateWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "ssl_rate_critical_percent",
+			Env:      "HAPROXY_SSL_RATE_CRITICAL_PERCENT",
+			Argument: "ssl-rate-critical-percent",
+			Default:  float32(0),
+			Usage:    "Process SSL session rate (SslRate of SslRateLimit) Critical percent, 0 to disable",
+			Value:    &plugin.SslRateCriticalPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "pipes_warning_percent",
+			Env:      "HAPROXY_PIPES_WARNING_PERCENT",
+			Argument: "pipes-warning-percent",
+			Default:  float32(0),
+			Usage:    "Process pipes (PipesUsed of Maxpipes) Warning percent, 0 to disable",
+			Value:    &plugin.PipesWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "pipes_critical_percent",
+			Env:      "HAPROXY_PIPES_CRITICAL_PERCENT",
+			Argument: "pipes-critical-percent",
+			Default:  float32(0),
+			Usage:    "Process pipes (PipesUsed of Maxpipes) Critical percent, 0 to disable",
+			Value:    &plugin.PipesCriticalPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "idle_warning_percent",
+			Env:      "HAPROXY_IDLE_WARNING_PERCENT",
+			Argument: "idle-warning-percent",
+			Default:  float32(0),
+			Usage:    "Process Idle_pct Warning when idle is below percent, 0 to disable",
+			Value:    &plugin.IdleWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "idle_critical_percent",
+			Env:      "HAPROXY_IDLE_CRITICAL_PERCENT",
+			Argument: "idle-critical-percent",
+			Default:  float32(0),
+			Usage:    "Process Idle_pct Critical when idle is below percent, 0 to disable",
+			Value:    &plugin.IdleCriticalPercent,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "state_dir",
+			Env:      "HAPROXY_STATE_DIR",
+			Argument: "state-dir",
+			Default:  "/var/cache/sensu/sensu-agent",
+			Usage:    "Directory to keep counters between runs, used by HTTP error thresholds, flapping detection and resolvers mode",
+			Value:    &plugin.StateDir,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "http_5xx_warning_percent",
+			Env:      "HAPROXY_HTTP_5XX_WARNING_PERCENT",
+			Argument: "http-5xx-warning-percent",
+			Default:  float32(0),
+			Usage:    "HTTP 5xx share of responses since the previous run Warning percent, 0 to disable",
+			Value:    &plugin.HTTP5xxWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "http_5xx_critical_percent",
+			Env:      "HAPROXY_HTTP_5XX_CRITICAL_PERCENT",
+			Argument: "http-5xx-critical-percent",
+			Default:  float32(0),
+			Usage:    "HTTP 5xx share of responses since the previous run Critical percent, 0 to disable",
+			Value:    &plugin.HTTP5xxCriticalPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "http_5xx_rate_warning",
+			Env:      "HAPROXY_HTTP_5XX_RATE_WARNING",
+			Argument: "http-5xx-rate-warning",
+			Default:  float32(0),
+			Usage:    "HTTP 5xx responses per second since the previous run Warning, 0 to disable",
+			Value:    &plugin.HTTP5xxRateWarning,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "http_5xx_rate_critical",
+			Env:      "HAPROXY_HTTP_5XX_RATE_CRITICAL",
+			Argument: "http-5xx-rate-critical",
+			Default:  float32(0),
+			Usage:    "HTTP 5xx responses per second since the previous run Critical, 0 to disable",
+			Value:    &plugin.HTTP5xxRateCritical,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "http_4xx_warning_percent",
+			Env:      "HAPROXY_HTTP_4XX_WARNING_PERCENT",
+			Argument: "http-4xx-warning-percent",
+			Default:  float32(0),
+			Usage:    "HTTP 4xx share of responses since the previous run Warning percent, 0 to disable",
+			Value:    &plugin.HTTP4xxWarningPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "http_4xx_critical_percent",
+			Env:      "HAPROXY_HTTP_4XX_CRITICAL_PERCENT",
+			Argument: "http-4xx-critical-percent",
+			Default:  float32(0),
+			Usage:    "HTTP 4xx share of responses since the previous run Critical percent, 0 to disable",
+			Value:    &plugin.HTTP4xxCriticalPercent,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "http_4xx_rate_warning",
+			Env:      "HAPROXY_HTTP_4XX_RATE_WARNING",
+			Argument: "http-4xx-rate-warning",
+			Default:  float32(0),
+			Usage:    "HTTP 4xx responses per second since the previous run Warning, 0 to disable",
+			Value:    &plugin.HTTP4xxRateWarning,
+		},
+		&sensu.PluginConfigOption[float32]{
+			Path:     "http_4xx_rate_critical",
+			Env:      "HAPROXY_HTTP_4XX_RATE_CRITICAL",
+			Argument: "http-4xx-rate-critical",
+			Default:  float32(0),
+			Usage:    "HTTP 4xx responses per second since the previous run Critical, 0 to disable",
+			Value:    &plugin.HTTP4xxRateCritical,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "http_min_responses",
+			Env:      "HAPROXY_HTTP_MIN_RESPONSES",
+			Argument: "http-min-responses",
+			Default:  0,
+			Usage:    "Minimum responses since the previous run to evaluate HTTP error percents",
+			Value:    &plugin.HTTPMinResponses,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "metrics_format",
+			Env:      "HAPROXY_METRICS_FORMAT",
+			Argument: "metrics-format",
+			Default:  "",
+			Allow:    []string{"", MetricsFormatPrometheus, MetricsFormatGraphite, MetricsFormatInfluxDB, MetricsFormatOpenTSDB, MetricsFormatNagios},
+			Usage:    "Print stats of selected services as metrics: prometheus_text, graphite_plaintext, influxdb_line, opentsdb_line or nagios_perfdata",
+			Value:    &plugin.MetricsFormat,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     "event_metrics",
+			Env:      "HAPROXY_EVENT_METRICS",
+			Argument: "event-metrics",
+			Default:  false,
+			Usage:    "Send stats of selected services as metric points to the agent events API (--agent-api-url) using output_metric_handlers and output_metric_tags of the check read from stdin",
+			Value:    &plugin.EventMetrics,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     "proxy_events",
+			Env:      "HAPROXY_PROXY_EVENTS",
+			Argument: "proxy-events",
+			Default:  false,
+			Usage:    "Send one event per proxy to the agent events API, the check itself reports dispatch only",
+			Value:    &plugin.ProxyEvents,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "proxy_entity",
+			Env:      "HAPROXY_PROXY_ENTITY",
+			Argument: "proxy-entity",
+			Default:  "",
+			Usage:    "Proxy entity name format for --proxy-events, {entity}, {instance} and {pxname} are replaced (default: {entity}-{instance}-{pxname} without empty parts)",
+			Value:    &plugin.ProxyEntity,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "agent_api_url",
+			Env:      "HAPROXY_AGENT_API_URL",
+			Argument: "agent-api-url",
+			Default:  "http://127.0.0.1:3031/events",
+			Usage:    "Sensu agent events API URL for --proxy-events and --event-metrics",
+			Value:    &plugin.AgentAPIURL,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "output",
+			Env:      "HAPROXY_OUTPUT",
+			Argument: "output",
+			Default:  OutputText,
+			Allow:    []string{OutputText, OutputJSON},
+			Usage:    "Result output: text log lines or json result document",
+			Value:    &plugin.Output,
+		},
 		&sensu.PluginConfigOption[bool]{
 			Path:      "debug",
 			Env:       "HAPROXY_DEBUG",
@@ -190,21 +901,100 @@ func main() {
 }
 
 func checkArgs(event *corev2.Event) (int, error) {
-	path, err := filepath.Abs(plugin.SocketPath)
-	if err != nil {
-		return sensu.CheckStateUnknown, fmt.Errorf("--socket error: %w", err)
+	if plugin.URL != "" {
+		u, err := url.Parse(plugin.URL)
+		if err != nil {
+			return sensu.CheckStateUnknown, fmt.Errorf("--url error: %w", err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return sensu.CheckStateUnknown, fmt.Errorf("--url: unsupported scheme: %s", u.Scheme)
+		}
+
+		if plugin.StatFormat == string(haproxy.StatFormatTyped) {
+			return sensu.CheckStateUnknown, fmt.Errorf("--stat-format: %s is not supported by stats page", plugin.StatFormat)
+		}
+
+		if (plugin.CertFile == "") != (plugin.KeyFile == "") {
+			return sensu.CheckStateUnknown, fmt.Errorf("--cert-file and --key-file should be used together")
+		}
+
+		instances = []*Instance{{
+			Name: u.Host,
+			HTTP: &haproxy.HTTPSource{
+				URL:                plugin.URL,
+				Username:           plugin.Username,
+				Password:           plugin.Password,
+				CAFile:             plugin.CAFile,
+				CertFile:           plugin.CertFile,
+				KeyFile:            plugin.KeyFile,
+				InsecureSkipVerify: plugin.InsecureSkipVerify,
+			},
+		}}
+	} else {
+		in, err := socketInstances(plugin.Sockets)
+		if err != nil {
+			return sensu.CheckStateUnknown, fmt.Errorf("--socket error: %w", err)
+		}
+		instances = in
 	}
 
-	fi, err := os.Lstat(path)
-	if err != nil {
-		return sensu.CheckStateUnknown, fmt.Errorf("--socket error: %w", err)
-	} else if fi.Mode()&os.ModeSocket == 0 {
-		return sensu.CheckStateUnknown, fmt.Errorf("--socket: %s is not socket: %v", path, fi.Mode())
+	if plugin.URL != "" && plugin.Master {
+		return sensu.CheckStateUnknown, fmt.Errorf("--master requires --socket")
+	}
+
+	if plugin.URL != "" && infoChecksEnabled() {
+		return sensu.CheckStateUnknown, fmt.Errorf("process-wide thresholds require --socket, stats page does not provide show info")
+	}
+
+	if plugin.EventMetrics {
+		if event == nil || event.Check == nil {
+			return sensu.CheckStateUnknown, fmt.Errorf("--event-metrics requires the event on stdin")
+		} else if plugin.MetricsFormat != "" {
+			return sensu.CheckStateUnknown, fmt.Errorf("Only one --metrics-format or --event-metrics should be used")
+		}
+	}
+
+	if plugin.Output == OutputJSON && (plugin.MetricsFormat != "" || plugin.EventMetrics) {
+		return sensu.CheckStateUnknown, fmt.Errorf("--output json can not be used with --metrics-format or --event-metrics")
+	}
+
+	if plugin.Mode != ModeStats && plugin.Mode != "" {
+		if plugin.URL != "" {
+			return sensu.CheckStateUnknown, fmt.Errorf("--mode %s requires --socket, stats page provides stats only", plugin.Mode)
+		} else if plugin.MetricsFormat != "" || plugin.EventMetrics || plugin.ProxyEvents || plugin.Output == OutputJSON {
+			return sensu.CheckStateUnknown, fmt.Errorf("--mode %s can not be used with --metrics-format, --event-metrics, --proxy-events or --output json", plugin.Mode)
+		}
+	}
+
+	if err := compileFilters(); err != nil {
+		return sensu.CheckStateUnknown, err
+	}
+
+	switch plugin.Mode {
+	case ModeSSLCert, ModePeers, ModeResolvers:
+		return sensu.CheckStateOK, nil
+	case ModeTable:
+		if plugin.TableTop > 0 && plugin.TableTopCounter == "" {
+			return sensu.CheckStateUnknown, fmt.Errorf("--table-top requires --table-top-counter")
+		}
+		return sensu.CheckStateOK, nil
+	}
+
+	rules = nil
+	if plugin.Rules != "" {
+		r, err := LoadRules(plugin.Rules, plugin.Thresholds)
+		if err != nil {
+			return sensu.CheckStateUnknown, fmt.Errorf("--rules error: %w", err)
+		}
+		rules = r
+	}
+
+	proxyOverrides = loadProxyOverrides(event)
+	if err := validateProxyOverrides(proxyOverrides); err != nil {
+		return sensu.CheckStateUnknown, fmt.Errorf("annotation error: %w", err)
 	}
-	plugin.SocketPath = path
 
-	if plugin.Service == "" && !plugin.AllServices {
-		return sensu.CheckStateWarning, fmt.Errorf("--service or --all-services are required")
+	if plugin.Service == "" && !plugin.AllServices && len(includeProxy) == 0 {
+		return sensu.CheckStateWarning, fmt.Errorf("--service, --all-services or --include-proxy are required")
 	} else if plugin.Service != "" && plugin.AllServices {
 		return sensu.CheckStateWarning, fmt.Errorf("Only one --service or --all-services should be used")
 	}
@@ -213,59 +1003,318 @@ func checkArgs(event *corev2.Event) (int, error) {
 }
 
 func executeCheck(event *corev2.Event) (int, error) {
-	stats, rawData, err := haproxy.GetStats(plugin.SocketPath)
-	if err != nil {
-		return sensu.CheckStateUnknown, fmt.Errorf("Failed to get service stats: %w", err)
+	switch plugin.Mode {
+	case ModeSSLCert:
+		return checkSSLCerts(time.Now())
+	case ModeTable:
+		return checkTables()
+	case ModePeers:
+		return checkPeers()
+	case ModeResolvers:
+		return checkResolvers(event)
 	}
 
-	// Leave only selected services
-	pxkeys := make([]string, 0)
-	for key := range stats {
-		if plugin.AllServices || key == plugin.Service {
-			pxkeys = append(pxkeys, key)
+	if plugin.Output != OutputJSON {
+		ret, _, err := runCheck(event)
+		return ret, err
+	}
+
+	// the document replaces the text output
+	var ret int
+	var report *Report
+	var err error
+	captureLog(func() { ret, report, err = runCheck(event) })
+
+	return ret, multierr.Append(err, writeReport(os.Stdout, ret, report, err))
+}
+
+// runCheck checks all instances and returns the report of checked processes and proxies
+func runCheck(event *corev2.Event) (int, *Report, error) {
+	results := fetchInstances(instances)
+
+	failed := 0
+	var err error
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			err = multierr.Append(err, r.Err)
+		}
+	}
+	if failed == len(results) {
+		return sensu.CheckStateUnknown, nil, err
+	}
+
+	if stateChecksEnabled() {
+		var err2 error
+		state, err2 = LoadState(stateFilePath(plugin.StateDir, sourceName(), checkName(event)), time.Now())
+		if err2 != nil {
+			return sensu.CheckStateUnknown, nil, err2
+		}
+	}
+
+	ret := sensu.CheckStateOK
+	if failed > 0 {
+		ret = sensu.CheckStateUnknown
+	}
+
+	found := 0
+	report := &Report{Proxies: make([]*ProxyReport, 0)}
+	points := make([]*corev2.MetricPoint, 0)
+	now := time.Now()
+	for _, r := range results {
+		if r.Err != nil {
 			continue
 		}
 
-		delete(stats, key)
+		if multiInstance() {
+			log.Printf("Instance %s:", r.Instance.Name)
+		}
+
+		newret, n, err2 := checkInstance(r)
+		ret = worstState(ret, newret)
+		err = multierr.Append(err, err2)
+		found += n
+		report.Proxies = append(report.Proxies, r.Reports...)
+		if r.Process != nil {
+			report.Processes = append(report.Processes, r.Process)
+		}
+
+		points = append(points, statMetrics(r.Instance.Tag(), r.Keys, r.Stats, now)...)
+	}
+
+	if plugin.ProxyEvents {
+		proxies := make([]proxyResult, 0)
+		for _, r := range results {
+			proxies = append(proxies, r.Proxies...)
+		}
+
+		if err2 := dispatchProxyEvents(event, proxies); err2 != nil {
+			ret = worstState(ret, sensu.CheckStateCritical)
+			err = multierr.Append(err, err2)
+		}
+	}
+
+	if plugin.MetricsFormat != "" {
+		err = multierr.Append(err, writeMetrics(os.Stdout, plugin.MetricsFormat, points))
+	} else if plugin.EventMetrics {
+		err = multierr.Append(err, sendEventMetrics(event, points, now))
+	}
+
+	if state != nil {
+		if state.Resets > 0 {
+			log.Printf("Counters reset for %d entries, HAProxy was reloaded", state.Resets)
+		}
+
+		err = multierr.Append(err, state.Save())
 	}
-	sort.Strings(pxkeys)
 
-	// No services
-	if len(stats) == 0 {
-		log.Printf("No service: %s", plugin.Service)
-		if plugin.MissingFail {
-			return sensu.CheckStateCritical, nil
-		} else if plugin.MissingOk {
-			return sensu.CheckStateOK, nil
+	// No services, counters read above are already saved
+	if found == 0 {
+		if len(plugin.IncludeProxy) > 0 {
+			log.Printf("No service: %s, matching: %s", plugin.Service, strings.Join(plugin.IncludeProxy, ", "))
+		} else {
+			log.Printf("No service: %s", plugin.Service)
+		}
+		th := thresholdsFor(plugin.Service)
+		if th.MissingFail {
+			return sensu.CheckStateCritical, report, err
+		} else if th.MissingOk {
+			return ret, report, err
 		}
 
-		return sensu.CheckStateUnknown, nil
+		return worstState(ret, sensu.CheckStateUnknown), report, err
 	}
 
+	return ret, report, err
+}
+
+// checkInstance checks process info and selected services of one instance.
+// It returns the number of selected services.
+func checkInstance(r *instanceResult) (int, int, error) {
 	ret := sensu.CheckStateOK
-	err = nil
-	for _, pxname := range pxkeys {
-		stat := stats[pxname]
-		newret, err2 := checkService(pxname, stat)
-		err = multierr.Append(err, err2)
-		if newret > ret {
-			ret = newret
+	if r.Info != nil {
+		r.Process = &ProcessReport{Instance: r.Instance.Tag()}
+		ret = checkInfo(r.Process, r.Info)
+		if plugin.Debug && ret > sensu.CheckStateOK {
+			log.Printf("Raw info data\n---\n%s", string(r.RawInfo))
+		}
+	}
+
+	// Leave only selected services
+	r.Keys = make([]string, 0)
+	for key, svc := range r.Stats {
+		if proxySelected(key) {
+			r.Stats[key] = filterServers(svc)
+			r.Keys = append(r.Keys, key)
+			continue
 		}
 
-		if plugin.Debug && (newret > sensu.CheckStateOK || err2 != nil) {
+		delete(r.Stats, key)
+	}
+	sort.Strings(r.Keys)
+
+	if state != nil {
+		state.Prefix = r.Instance.statePrefix()
+	}
+
+	var err error
+	for _, pxname := range r.Keys {
+		stat := r.Stats[pxname]
+
+		var pr *ProxyReport
+		var err2 error
+		if plugin.ProxyEvents {
+			// the proxy status goes to its own event, the summary reports dispatch only
+			output := captureLog(func() { pr, err2 = checkProxy(r.Instance.Tag(), pxname, stat) })
+			newret := pr.Status
+			if err2 != nil {
+				output += err2.Error() + "\n"
+				newret = worstState(newret, sensu.CheckStateUnknown)
+			}
+			r.Proxies = append(r.Proxies, proxyResult{
+				Instance: r.Instance.Tag(),
+				Pxname:   pxname,
+				Status:   newret,
+				Output:   output,
+			})
+		} else {
+			pr, err2 = checkProxy(r.Instance.Tag(), pxname, stat)
+			err = multierr.Append(err, err2)
+			ret = worstState(ret, pr.Status)
+		}
+		r.Reports = append(r.Reports, pr)
+
+		if plugin.Debug && (pr.Status > sensu.CheckStateOK || err2 != nil) {
 			b, _ := json.Marshal(&stat)
 			log.Print(string(b))
 		}
 	}
 
-	if plugin.Debug && (ret > sensu.CheckStateOK || err != nil) {
-		log.Printf("Raw stat data\n---\n%s", string(rawData))
+	if plugin.Debug && len(r.Keys) > 0 && (ret > sensu.CheckStateOK || err != nil) {
+		log.Printf("Raw stat data\n---\n%s", string(r.RawData))
+	}
+
+	return ret, len(r.Keys), err
+}
+
+// sourceName identifies the configured sources for the state file
+func sourceName() string {
+	if plugin.URL != "" {
+		return plugin.URL
+	}
+
+	return strings.Join(plugin.Sockets, ",")
+}
+
+// checkName returns the check name of the event, if any
+func checkName(event *corev2.Event) string {
+	if event == nil || event.Check == nil {
+		return ""
 	}
 
-	return ret, err
+	return event.Check.Name
 }
 
+// checkService checks one proxy and returns its state
 func checkService(pxname string, svc haproxy.StatService) (int, error) {
+	pr, err := checkProxy("", pxname, svc)
+	return pr.Status, err
+}
+
+// checkProxy checks one proxy and reports its outcome
+func checkProxy(instance, pxname string, svc haproxy.StatService) (*ProxyReport, error) {
+	pr := &ProxyReport{Instance: instance, Pxname: pxname}
+	th := thresholdsFor(pxname)
+
+	ret := max(checkFrontend(pr, svc, th), checkBackend(pr, svc, th))
+	ret = max(ret, checkThresholds(pr, backendAndServers(svc), append(queueThresholds(th), timeThresholds(th)...)))
+	if state != nil {
+		ret = max(ret, checkThresholds(pr, svc, httpErrorThresholds(httpDeltas(pxname, svc), state.Elapsed(), th)))
+	}
+	ret = max(ret, checkFlapping(pr, svc, th))
+
+	newret, err := checkServers(pr, svc, th)
+	pr.setStatus(max(ret, newret))
+	if err != nil {
+		pr.Error = err.Error()
+	}
+
+	return pr, err
+}
+
+// backendAndServers leaves BACKEND and server entries
+func backendAndServers(svc haproxy.StatService) haproxy.StatService {
+	entries := svc.Servers()
+	if backend, ok := svc[haproxy.Backend]; ok {
+		entries[haproxy.Backend] = backend
+	}
+
+	return entries
+}
+
+// sessionsEntry describes session usage of the entry
+func sessionsEntry(s haproxy.StatLine) string {
+	return fmt.Sprintf("%s: %d of %d (%.0f%%) sessions", s.LogName(), s.Scur.Int64, s.Slim.Int64, s.SessionLimitPercentage())
+}
+
+// tripSessions logs and reports entries over the session limit threshold
+func tripSessions(pr *ProxyReport, name string, state int, limit float32, entries haproxy.StatService) {
+	lines := make([]string, 0, len(entries))
+	log.Printf("%s %s:", name, strings.ToLower(stateName(state)))
+	for _, s := range entries.Lines() {
+		lines = append(lines, sessionsEntry(s))
+		log.Printf("\t%s", lines[len(lines)-1])
+	}
+
+	pr.trip(name, state, fmt.Sprintf("%d entries over %.0f%% of session limit", len(entries), limit), lines...)
+}
+
+// checkFrontend checks FRONTEND and listener session limits (maxconn)
+func checkFrontend(pr *ProxyReport, svc haproxy.StatService, th *Thresholds) int {
+	listeners := svc.Listeners().Filter(func(s haproxy.StatLine) bool {
+		return s.HasSessionLimit()
+	})
+
+	criticalSessions := listeners.Filter(func(s haproxy.StatLine) bool {
+		return th.FrontendSessionCriticalPercent > 0 && s.SessionLimitPercentage() > th.FrontendSessionCriticalPercent
+	})
+
+	warningSessions := listeners.Filter(func(s haproxy.StatLine) bool {
+		return th.FrontendSessionWarningPercent > 0 && s.SessionLimitPercentage() > th.FrontendSessionWarningPercent
+	})
+
+	if len(criticalSessions) > 0 {
+		tripSessions(pr, "Frontend sessions", sensu.CheckStateCritical, th.FrontendSessionCriticalPercent, criticalSessions)
+		return sensu.CheckStateCritical
+	} else if len(warningSessions) > 0 {
+		tripSessions(pr, "Frontend sessions", sensu.CheckStateWarning, th.FrontendSessionWarningPercent, warningSessions)
+		return sensu.CheckStateWarning
+	}
+
+	return sensu.CheckStateOK
+}
+
+// checkBackend checks BACKEND session limit (fullconn)
+func checkBackend(pr *ProxyReport, svc haproxy.StatService, th *Thresholds) int {
+	backend, ok := svc[haproxy.Backend]
+	if !ok || !backend.HasSessionLimit() {
+		return sensu.CheckStateOK
+	}
+
+	pct := backend.SessionLimitPercentage()
+	state := thresholdState(pct, th.BackendSessionWarningPercent, th.BackendSessionCriticalPercent)
+	switch state {
+	case sensu.CheckStateCritical:
+		tripSessions(pr, "Backend sessions", state, th.BackendSessionCriticalPercent, haproxy.StatService{haproxy.Backend: backend})
+	case sensu.CheckStateWarning:
+		tripSessions(pr, "Backend sessions", state, th.BackendSessionWarningPercent, haproxy.StatService{haproxy.Backend: backend})
+	}
+
+	return state
+}
+
+// checkServers checks server availability and server session limits
+func checkServers(pr *ProxyReport, svc haproxy.StatService, th *Thresholds) (int, error) {
 	servers := svc.Servers()
 	backend, backendOk := svc[haproxy.Backend]
 
@@ -274,60 +1323,135 @@ func checkService(pxname string, svc haproxy.StatService) (int, error) {
 		backendPtr = &backend
 	}
 
-	// Ignore FRONTEND-only entries
-	if len(servers) == 0 && plugin.AllServices {
+	// Ignore FRONTEND-only entries, unless the service is requested by name
+	if len(servers) == 0 && pr.Pxname != plugin.Service {
 		return sensu.CheckStateOK, nil
 	}
 
 	upCount := 0
 	failedNames := make([]string, 0)
+	ignoredNames := make([]string, 0)
 
-	for _, s := range servers {
-		if s.IsUp(backendPtr) {
+	for _, s := range servers.Lines() {
+		switch serverPolicy(s, backendPtr, th) {
+		case PolicyUp:
 			upCount++
-		} else {
+		case PolicyIgnore:
+			ignoredNames = append(ignoredNames, ignoredName(s))
+			pr.Ignored = append(pr.Ignored, newServerReport(s))
+			delete(servers, s.Svname)
+		default:
 			failedNames = append(failedNames, s.LogName())
+			pr.Failed = append(pr.Failed, newServerReport(s))
 		}
 	}
 
 	upPercent := 100.0 * float32(upCount) / float32(len(servers))
+	if len(servers) == 0 && len(ignoredNames) > 0 {
+		// all servers are excluded on purpose, e.g. in maintenance
+		upPercent = 100.0
+	}
+
+	pr.Servers = len(servers)
+	pr.Up = upCount
+	if len(servers) > 0 || len(ignoredNames) > 0 {
+		pr.UpPercent = &upPercent
+	}
 
 	criticalSesions := servers.Filter(func(s haproxy.StatLine) bool {
-		return s.Slim > 0 && s.SessionLimitPercentage() > plugin.SessionCriticalPercent
+		return s.HasSessionLimit() && s.SessionLimitPercentage() > th.SessionCriticalPercent
 	})
 
 	warningSesions := servers.Filter(func(s haproxy.StatLine) bool {
-		return s.Slim > 0 && s.SessionLimitPercentage() > plugin.SessionWarningPercent
+		return s.HasSessionLimit() && s.SessionLimitPercentage() > th.SessionWarningPercent
 	})
 
-	log.Printf("UP: %.0f%% of #%d %s services", upPercent, len(servers), pxname)
+	log.Printf("UP: %.0f%% of #%d %s services", upPercent, len(servers), pr.Pxname)
 	if len(failedNames) > 0 {
 		log.Printf("DOWN: %s", strings.Join(failedNames, ", "))
 	}
+	if len(ignoredNames) > 0 {
+		log.Printf("IGNORED: %s", strings.Join(ignoredNames, ", "))
+	}
 
-	if len(servers) < plugin.MinCriticalCount {
+	if len(servers) < th.MinCriticalCount {
+		pr.trip("Servers", sensu.CheckStateCritical, fmt.Sprintf("%d servers, less than %d", len(servers), th.MinCriticalCount))
 		return sensu.CheckStateCritical, nil
-	} else if upPercent < plugin.CriticalPercent {
+	} else if upPercent < th.CriticalPercent {
+		pr.trip("UP", sensu.CheckStateCritical, fmt.Sprintf("%.0f%% servers up, less than %.0f%%", upPercent, th.CriticalPercent), failedNames...)
 		return sensu.CheckStateCritical, nil
 	} else if len(criticalSesions) > 0 {
-		log.Printf("Active sessions critical:")
-		for _, s := range criticalSesions {
-			log.Printf("\t%s: %d of %d (%.0f%%) sessions", s.LogName(), s.Scur, s.Slim, s.SessionLimitPercentage())
-		}
+		tripSessions(pr, "Active sessions", sensu.CheckStateCritical, th.SessionCriticalPercent, criticalSesions)
 		return sensu.CheckStateCritical, nil
 	}
 
-	if len(servers) < plugin.MinWarningCount {
+	if len(servers) < th.MinWarningCount {
+		pr.trip("Servers", sensu.CheckStateWarning, fmt.Sprintf("%d servers, less than %d", len(servers), th.MinWarningCount))
 		return sensu.CheckStateWarning, nil
-	} else if upPercent < plugin.WarningPercent {
+	} else if upPercent < th.WarningPercent {
+		pr.trip("UP", sensu.CheckStateWarning, fmt.Sprintf("%.0f%% servers up, less than %.0f%%", upPercent, th.WarningPercent), failedNames...)
 		return sensu.CheckStateWarning, nil
-	} else if len(criticalSesions) > 0 {
-		log.Printf("Active sessions warning:")
-		for _, s := range warningSesions {
-			log.Printf("\t%s: %d of %d (%.0f%%) sessions", s.LogName(), s.Scur, s.Slim, s.SessionLimitPercentage())
-		}
+	} else if len(warningSesions) > 0 {
+		tripSessions(pr, "Active sessions", sensu.CheckStateWarning, th.SessionWarningPercent, warningSesions)
 		return sensu.CheckStateWarning, nil
 	}
 
 	return sensu.CheckStateOK, nil
 }
+
+// thresholdState compares value with thresholds, zero threshold is disabled
+func thresholdState(value, warning, critical float32) int {
+	if critical > 0 && value > critical {
+		return sensu.CheckStateCritical
+	} else if warning > 0 && value > warning {
+		return sensu.CheckStateWarning
+	}
+
+	return sensu.CheckStateOK
+}
+
+// stateSeverity orders check states from the best to the worst
+var stateSeverity = map[int]int{
+	sensu.CheckStateOK:       0,
+	sensu.CheckStateWarning:  1,
+	sensu.CheckStateUnknown:  2,
+	sensu.CheckStateCritical: 3,
+}
+
+// worstState merges check states: CRITICAL > UNKNOWN > WARNING > OK,
+// so a failed source does not hide a CRITICAL result of another one
+func worstState(a, b int) int {
+	if stateSeverity[b] > stateSeverity[a] {
+		return b
+	}
+
+	return a
+}
+
+// logGroup logs descriptions under "<title> <state>:" heading and returns the state, OK if there are none
+func logGroup(title string, state int, descs []string) int {
+	if len(descs) == 0 {
+		return sensu.CheckStateOK
+	}
+
+	log.Printf("%s %s:", title, strings.ToLower(stateName(state)))
+	for _, desc := range descs {
+		log.Printf("\t%s", desc)
+	}
+
+	return state
+}
+
+// stateName makes a log prefix for the check state
+func stateName(state int) string {
+	switch state {
+	case sensu.CheckStateOK:
+		return "OK"
+	case sensu.CheckStateWarning:
+		return "WARNING"
+	case sensu.CheckStateCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
@@ -1,7 +1,17 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
 )
 
 const testingCSV = `
@@ -22,3 +32,196 @@ ipmi_exporter,BACKEND,0,0,0,6,10000,5049,1337424,6430583,0,0,,0,0,0,0,UP,3,3,0,,
 func TestMain(t *testing.T) {
 	_ = testingCSV
 }
+
+func TestExecuteCheckHTTP(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(strings.TrimPrefix(testingCSV, "\n")))
+		assert.NoError(err)
+	}))
+	defer srv.Close()
+
+	plugin.URL = srv.URL + "/haproxy?stats"
+	plugin.AllServices = true
+	defer func() {
+		plugin.URL = ""
+		plugin.AllServices = false
+		instances = nil
+	}()
+
+	status, err := checkArgs(nil)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+
+	status, err = executeCheck(nil)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+}
+
+func TestRunCheckMissingService(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	defer func() { instances = nil }()
+	defer func(saved *StateStore) { state = saved }(state)
+
+	path := filepath.Join(t.TempDir(), "haproxy.sock")
+	serveStats(t, path, strings.TrimPrefix(testingCSV, "\n"))
+
+	plugin = Config{
+		Sockets:    []string{path},
+		StatFormat: string(haproxy.StatFormatCSV),
+		Service:    "no_such_service",
+		StateDir:   t.TempDir(),
+		Thresholds: Thresholds{HTTP5xxWarningPercent: 5},
+	}
+
+	_, err := checkArgs(nil)
+	if !assert.NoError(err) {
+		return
+	}
+
+	status, _, err := runCheck(nil)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateUnknown, status)
+
+	// the state is saved before the missing service is reported
+	_, err = os.Stat(stateFilePath(plugin.StateDir, sourceName(), ""))
+	assert.NoError(err)
+
+	plugin.MissingOk = true
+	status, _, err = runCheck(nil)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+}
+
+func testingStats(t *testing.T) haproxy.Stats {
+	stats, _, err := haproxy.ParseStatCSV(strings.NewReader(strings.TrimPrefix(testingCSV, "\n")))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	return stats
+}
+
+func TestCheckFrontend(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{
+		AllServices: true,
+		Thresholds: Thresholds{
+			FrontendSessionWarningPercent:  75,
+			FrontendSessionCriticalPercent: 90,
+		},
+	}
+
+	stats := testingStats(t)
+
+	// FRONTEND-only proxy is not skipped
+	status, err := checkService("https", stats["https"])
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+
+	fe := stats["https"][haproxy.Frontend]
+	fe.Scur = haproxy.NewInt64(80000)
+	stats["https"][haproxy.Frontend] = fe
+
+	status, err = checkService("https", stats["https"])
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateWarning, status)
+
+	fe.Scur = haproxy.NewInt64(99000)
+	stats["https"][haproxy.Frontend] = fe
+
+	status, err = checkService("https", stats["https"])
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateCritical, status)
+
+	plugin.FrontendSessionCriticalPercent = 0
+	plugin.FrontendSessionWarningPercent = 0
+	status, err = checkService("https", stats["https"])
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+}
+
+func TestCheckBackend(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{
+		AllServices: true,
+	}
+
+	stats := testingStats(t)
+	svc := stats["ipmi_exporter"]
+
+	bk := svc[haproxy.Backend]
+	bk.Scur = haproxy.NewInt64(8000)
+	svc[haproxy.Backend] = bk
+
+	// disabled by default
+	status, err := checkService("ipmi_exporter", svc)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+
+	plugin.BackendSessionWarningPercent = 75
+	plugin.BackendSessionCriticalPercent = 90
+	status, err = checkService("ipmi_exporter", svc)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateWarning, status)
+
+	bk.Scur = haproxy.NewInt64(9500)
+	svc[haproxy.Backend] = bk
+	status, err = checkService("ipmi_exporter", svc)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateCritical, status)
+}
+
+func TestCheckServerSessions(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{
+		AllServices: true,
+		Thresholds: Thresholds{
+			SessionWarningPercent:  75,
+			SessionCriticalPercent: 90,
+		},
+	}
+
+	stats := testingStats(t)
+	svc := stats["ipmi_exporter"]
+
+	srv := svc["ctrl01"]
+	srv.Slim = haproxy.NewInt64(100)
+	srv.Scur = haproxy.NewInt64(50)
+	svc["ctrl01"] = srv
+
+	status, err := checkService("ipmi_exporter", svc)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+
+	// between warning and critical
+	srv.Scur = haproxy.NewInt64(80)
+	svc["ctrl01"] = srv
+	status, err = checkService("ipmi_exporter", svc)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateWarning, status)
+
+	srv.Scur = haproxy.NewInt64(95)
+	svc["ctrl01"] = srv
+	status, err = checkService("ipmi_exporter", svc)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateCritical, status)
+}
+
+func TestWorstState(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(sensu.CheckStateWarning, worstState(sensu.CheckStateOK, sensu.CheckStateWarning))
+	assert.Equal(sensu.CheckStateUnknown, worstState(sensu.CheckStateUnknown, sensu.CheckStateWarning))
+	assert.Equal(sensu.CheckStateCritical, worstState(sensu.CheckStateUnknown, sensu.CheckStateCritical))
+	assert.Equal(sensu.CheckStateCritical, worstState(sensu.CheckStateCritical, sensu.CheckStateUnknown))
+}
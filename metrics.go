@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev2 "github.com/sensu/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// Output metric formats, same names as Sensu output metric extraction uses
+const (
+	MetricsFormatPrometheus = "prometheus_text"
+	MetricsFormatGraphite   = "graphite_plaintext"
+	MetricsFormatInfluxDB   = "influxdb_line"
+	MetricsFormatOpenTSDB   = "opentsdb_line"
+	MetricsFormatNagios     = "nagios_perfdata"
+)
+
+// metricsPrefix is the first part of all metric names
+const metricsPrefix = "haproxy"
+
+// metricsSkip are identity columns which are not metrics
+var metricsSkip = map[string]bool{
+	"pid":     true,
+	"iid":     true,
+	"sid":     true,
+	"type":    true,
+	"tracked": true,
+}
+
+// statMetrics makes metric points from numeric columns of the selected services.
+// Servers and BACKEND also get haproxy_up metric. Instance tag is added if set.
+func statMetrics(instance string, pxkeys []string, stats haproxy.Stats, now time.Time) []*corev2.MetricPoint {
+	points := make([]*corev2.MetricPoint, 0)
+	for _, pxname := range pxkeys {
+		svc := stats[pxname]
+
+		var backendPtr *haproxy.StatLine
+		if backend, ok := svc[haproxy.Backend]; ok {
+			backendPtr = &backend
+		}
+
+		for _, l := range svc.Lines() {
+			tags := make([]*corev2.MetricTag, 0, 5)
+			if instance != "" {
+				tags = append(tags, &corev2.MetricTag{Name: "instance", Value: instance})
+			}
+			tags = append(tags,
+				&corev2.MetricTag{Name: "pxname", Value: l.Pxname},
+				&corev2.MetricTag{Name: "svname", Value: l.Svname},
+			)
+			if name := l.TypeName(); name != "" {
+				tags = append(tags, &corev2.MetricTag{Name: "type", Value: name})
+			}
+			if l.Mode != "" {
+				tags = append(tags, &corev2.MetricTag{Name: "mode", Value: l.Mode})
+			}
+
+			values := l.Values()
+			names := make([]string, 0, len(values))
+			for name := range values {
+				if !metricsSkip[name] {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+
+			if l.Svname != haproxy.Frontend && !l.IsListener() {
+				up := 0.0
+				if l.IsUp(backendPtr) {
+					up = 1.0
+				}
+				points = append(points, metricPoint("up", up, now, tags))
+			}
+
+			for _, name := range names {
+				points = append(points, metricPoint(name, float64(values[name]), now, tags))
+			}
+		}
+	}
+
+	return points
+}
+
+func metricPoint(name string, value float64, now time.Time, tags []*corev2.MetricTag) *corev2.MetricPoint {
+	return &corev2.MetricPoint{
+		Name:      metricsPrefix + "_" + name,
+		Value:     value,
+		Timestamp: now.UnixNano(),
+		Tags:      tags,
+	}
+}
+
+// writeMetrics renders metric points in the format
+func writeMetrics(w io.Writer, format string, points []*corev2.MetricPoint) error {
+	var b strings.Builder
+
+	switch format {
+	case MetricsFormatPrometheus:
+		for _, p := range points {
+			labels := make([]string, 0, len(p.Tags))
+			for _, t := range p.Tags {
+				labels = append(labels, fmt.Sprintf(`%s="%s"`, t.Name, prometheusEscaper.Replace(t.Value)))
+			}
+			fmt.Fprintf(&b, "%s{%s} %s %d\n", p.Name, strings.Join(labels, ","), formatValue(p.Value), p.Timestamp/int64(time.Millisecond))
+		}
+
+	case MetricsFormatGraphite:
+		for _, p := range points {
+			fmt.Fprintf(&b, "%s %s %d\n", graphitePath(p), formatValue(p.Value), p.Timestamp/int64(time.Second))
+		}
+
+	case MetricsFormatInfluxDB:
+		for _, p := range points {
+			b.WriteString(p.Name)
+			for _, t := range p.Tags {
+				fmt.Fprintf(&b, ",%s=%s", t.Name, influxEscaper.Replace(t.Value))
+			}
+			fmt.Fprintf(&b, " value=%s %d\n", formatValue(p.Value), p.Timestamp)
+		}
+
+	case MetricsFormatOpenTSDB:
+		for _, p := range points {
+			fmt.Fprintf(&b, "put %s %d %s", p.Name, p.Timestamp/int64(time.Second), formatValue(p.Value))
+			for _, t := range p.Tags {
+				fmt.Fprintf(&b, " %s=%s", t.Name, sanitizeMetricName(t.Value))
+			}
+			b.WriteString("\n")
+		}
+
+	case MetricsFormatNagios:
+		perf := make([]string, 0, len(points))
+		for _, p := range points {
+			perf = append(perf, fmt.Sprintf("%s=%s", graphitePath(p), formatValue(p.Value)))
+		}
+		fmt.Fprintf(&b, "HAProxy stats | %s\n", strings.Join(perf, " "))
+
+	default:
+		return fmt.Errorf("unsupported metrics format: %s", format)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// metricsEvent makes an agent API event with metric points for the check output_metric_handlers,
+// output_metric_tags are added to the points. The event check is "<check>-metrics" without handlers,
+// so it does not replace the result of the check itself.
+func metricsEvent(event *corev2.Event, points []*corev2.MetricPoint, now time.Time) *corev2.Event {
+	check := &corev2.Check{
+		ObjectMeta: corev2.ObjectMeta{
+			Name:      event.Check.Name + "-metrics",
+			Namespace: event.Check.Namespace,
+		},
+		Status:   uint32(sensu.CheckStateOK),
+		Output:   fmt.Sprintf("Metric points: %d", len(points)),
+		Executed: now.Unix(),
+		Interval: event.Check.Interval,
+	}
+
+	for _, p := range points {
+		p.Tags = append(p.Tags[:len(p.Tags):len(p.Tags)], event.Check.OutputMetricTags...)
+	}
+
+	return &corev2.Event{
+		Timestamp: now.Unix(),
+		Check:     check,
+		Metrics: &corev2.Metrics{
+			Handlers: event.Check.OutputMetricHandlers,
+			Points:   points,
+		},
+	}
+}
+
+// sendEventMetrics posts metric points to the agent events API
+func sendEventMetrics(event *corev2.Event, points []*corev2.MetricPoint, now time.Time) error {
+	if err := postEvent(metricsEvent(event, points, now)); err != nil {
+		return fmt.Errorf("Failed to send metrics: %w", err)
+	}
+
+	log.Printf("Metric points sent: %d", len(points))
+	return nil
+}
+
+var prometheusEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+var influxEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// graphitePath makes a dotted name: haproxy.[<instance>.]<pxname>.<svname>.<column>
+func graphitePath(p *corev2.MetricPoint) string {
+	parts := []string{metricsPrefix}
+	for _, t := range p.Tags {
+		if t.Name == "instance" || t.Name == "pxname" || t.Name == "svname" {
+			parts = append(parts, sanitizeMetricName(t.Value))
+		}
+	}
+	parts = append(parts, strings.TrimPrefix(p.Name, metricsPrefix+"_"))
+
+	return strings.Join(parts, ".")
+}
+
+// sanitizeMetricName replaces characters which are not allowed in metric path elements
+func sanitizeMetricName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
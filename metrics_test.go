@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev2 "github.com/sensu/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	stats := testingStats(t)
+	now := time.Unix(1700000000, 0)
+
+	points := statMetrics("", []string{"ipmi_exporter"}, stats, now)
+	byName := make(map[string]float64)
+	for _, p := range points {
+		assert.Equal(now.UnixNano(), p.Timestamp)
+		byName[graphitePath(p)] = p.Value
+	}
+
+	assert.Equal(1.0, byName["haproxy.ipmi_exporter.ctrl01.up"])
+	assert.Equal(1.0, byName["haproxy.ipmi_exporter.BACKEND.up"])
+	assert.NotContains(byName, "haproxy.ipmi_exporter.FRONTEND.up")
+	assert.Equal(5049.0, byName["haproxy.ipmi_exporter.BACKEND.hrsp_2xx"])
+	assert.Equal(1752.0, byName["haproxy.ipmi_exporter.ctrl01.rtime"])
+	assert.NotContains(byName, "haproxy.ipmi_exporter.ctrl01.pid")
+	assert.NotContains(byName, "haproxy.ipmi_exporter.ctrl01.qlimit")
+
+	points = statMetrics("edge", []string{"ipmi_exporter"}, stats, now)
+	assert.Equal("instance", points[0].Tags[0].Name)
+	assert.Equal("haproxy.edge.ipmi_exporter.BACKEND.up", graphitePath(points[0]))
+}
+
+func TestWriteMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	stats := testingStats(t)
+	now := time.Unix(1700000000, 0)
+
+	all := statMetrics("", []string{"ipmi_exporter"}, stats, now)
+	points := all[:0]
+	for _, p := range all {
+		if p.Name == "haproxy_scur" && p.Tags[1].Value == "FRONTEND" {
+			points = append(points, p)
+		}
+	}
+	if !assert.Len(points, 1) {
+		return
+	}
+
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{MetricsFormatPrometheus, `haproxy_scur{pxname="ipmi_exporter",svname="FRONTEND",type="frontend",mode="http"} 6 1700000000000` + "\n"},
+		{MetricsFormatGraphite, "haproxy.ipmi_exporter.FRONTEND.scur 6 1700000000\n"},
+		{MetricsFormatInfluxDB, "haproxy_scur,pxname=ipmi_exporter,svname=FRONTEND,type=frontend,mode=http value=6 1700000000000000000\n"},
+		{MetricsFormatOpenTSDB, "put haproxy_scur 1700000000 6 pxname=ipmi_exporter svname=FRONTEND type=frontend mode=http\n"},
+		{MetricsFormatNagios, "HAProxy stats | haproxy.ipmi_exporter.FRONTEND.scur=6\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var b strings.Builder
+			assert.NoError(writeMetrics(&b, tc.format, points))
+			assert.Equal(tc.expected, b.String())
+		})
+	}
+
+	assert.Error(writeMetrics(&strings.Builder{}, "xml", points))
+}
+
+func TestEventMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	defer func() { instances = nil }()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(strings.TrimPrefix(testingCSV, "\n")))
+		assert.NoError(err)
+	}))
+	defer srv.Close()
+
+	var out *corev2.Event
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out = &corev2.Event{}
+		assert.NoError(json.NewDecoder(r.Body).Decode(out))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer agent.Close()
+
+	plugin = Config{
+		URL:          srv.URL + "/haproxy?stats",
+		Service:      "ipmi_exporter",
+		EventMetrics: true,
+		AgentAPIURL:  agent.URL + "/events",
+	}
+
+	event := corev2.FixtureEvent("entity1", "haproxy")
+	event.Check.Handlers = []string{"slack"}
+	event.Check.OutputMetricHandlers = []string{"influxdb"}
+	event.Check.OutputMetricTags = []*corev2.MetricTag{{Name: "dc", Value: "dc1"}}
+
+	_, err := checkArgs(event)
+	if !assert.NoError(err) {
+		return
+	}
+
+	status, err := executeCheck(event)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+
+	if !assert.NotNil(out) {
+		return
+	}
+
+	assert.Equal("haproxy-metrics", out.Check.Name)
+	assert.Equal(uint32(sensu.CheckStateOK), out.Check.Status)
+	assert.Empty(out.Check.Handlers)
+	assert.Equal([]string{"influxdb"}, out.Metrics.Handlers)
+	if assert.NotEmpty(out.Metrics.Points) {
+		p := out.Metrics.Points[0]
+		assert.Equal("haproxy_up", p.Name)
+		assert.Equal([]*corev2.MetricTag{
+			{Name: "pxname", Value: "ipmi_exporter"},
+			{Name: "svname", Value: "BACKEND"},
+			{Name: "type", Value: "backend"},
+			{Name: "mode", Value: "http"},
+			{Name: "dc", Value: "dc1"},
+		}, p.Tags)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"go.uber.org/multierr"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// Result output modes
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Report is the result document printed with --output json
+type Report struct {
+	Status    int              `json:"status"`
+	State     string           `json:"state"`
+	Processes []*ProcessReport `json:"processes,omitempty"`
+	Proxies   []*ProxyReport   `json:"proxies"`
+	Errors    []string         `json:"errors,omitempty"`
+}
+
+// ProcessReport is the outcome of process-wide thresholds of one instance
+type ProcessReport struct {
+	Instance string    `json:"instance,omitempty"`
+	Status   int       `json:"status"`
+	State    string    `json:"state"`
+	Tripped  []Tripped `json:"tripped,omitempty"`
+}
+
+// ProxyReport is the outcome of one proxy check
+type ProxyReport struct {
+	Instance  string         `json:"instance,omitempty"`
+	Pxname    string         `json:"pxname"`
+	Status    int            `json:"status"`
+	State     string         `json:"state"`
+	Servers   int            `json:"servers"`
+	Up        int            `json:"up"`
+	UpPercent *float32       `json:"up_percent,omitempty"`
+	Failed    []ServerReport `json:"failed,omitempty"`
+	Ignored   []ServerReport `json:"ignored,omitempty"`
+	Tripped   []Tripped      `json:"tripped,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// ServerReport is a failed server or a server excluded from availability by policy
+type ServerReport struct {
+	Svname      string `json:"svname"`
+	Status      string `json:"status"`
+	CheckStatus string `json:"check_status,omitempty"`
+	CheckDesc   string `json:"check_desc,omitempty"`
+}
+
+// Tripped is a threshold which raised the proxy state.
+// Entries list the offending entries, if any.
+type Tripped struct {
+	Name    string   `json:"name"`
+	State   string   `json:"state"`
+	Reason  string   `json:"reason"`
+	Entries []string `json:"entries,omitempty"`
+}
+
+func newServerReport(l haproxy.StatLine) ServerReport {
+	return ServerReport{
+		Svname:      l.Svname,
+		Status:      l.Status,
+		CheckStatus: l.CheckStatus,
+		CheckDesc:   l.CheckDesc,
+	}
+}
+
+func newTripped(name string, state int, reason string, entries []string) Tripped {
+	return Tripped{
+		Name:    name,
+		State:   stateName(state),
+		Reason:  reason,
+		Entries: entries,
+	}
+}
+
+// trip records a tripped threshold
+func (pr *ProxyReport) trip(name string, state int, reason string, entries ...string) {
+	pr.Tripped = append(pr.Tripped, newTripped(name, state, reason, entries))
+}
+
+// setStatus sets the final proxy state
+func (pr *ProxyReport) setStatus(status int) {
+	pr.Status = status
+	pr.State = stateName(status)
+}
+
+// trip records a tripped process-wide threshold
+func (pr *ProcessReport) trip(name string, state int, reason string) {
+	pr.Tripped = append(pr.Tripped, newTripped(name, state, reason, nil))
+}
+
+// setStatus sets the final process state
+func (pr *ProcessReport) setStatus(status int) {
+	pr.Status = status
+	pr.State = stateName(status)
+}
+
+// writeReport prints the result document of all checked processes and proxies
+func writeReport(w io.Writer, status int, report *Report, err error) error {
+	if report == nil {
+		report = &Report{}
+	}
+	report.Status = status
+	report.State = stateName(status)
+	if report.Proxies == nil {
+		report.Proxies = make([]*ProxyReport, 0)
+	}
+	for _, e := range multierr.Errors(err) {
+		report.Errors = append(report.Errors, e.Error())
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("report marshal error: %w", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
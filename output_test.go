@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckProxyReport(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{Thresholds: Thresholds{WarningPercent: 80, CriticalPercent: 25, TotalTimeWarning: 1700}}
+
+	svc := testingStats(t)["ipmi_exporter"]
+	srv := svc["ctrl02"]
+	srv.Status = "DOWN"
+	srv.CheckStatus = "L4CON"
+	srv.CheckDesc = "Layer4 connection problem"
+	svc["ctrl02"] = srv
+
+	pr, err := checkProxy("edge", "ipmi_exporter", svc)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(sensu.CheckStateWarning, pr.Status)
+	assert.Equal("WARNING", pr.State)
+	assert.Equal(3, pr.Servers)
+	assert.Equal(2, pr.Up)
+	if assert.NotNil(pr.UpPercent) {
+		assert.InDelta(66.7, *pr.UpPercent, 0.1)
+	}
+	assert.Equal([]ServerReport{{Svname: "ctrl02", Status: "DOWN", CheckStatus: "L4CON", CheckDesc: "Layer4 connection problem"}}, pr.Failed)
+
+	if assert.Len(pr.Tripped, 2) {
+		assert.Equal("Total time", pr.Tripped[0].Name)
+		assert.Equal("WARNING", pr.Tripped[0].State)
+		assert.Equal("UP", pr.Tripped[1].Name)
+		assert.Equal("67% servers up, less than 80%", pr.Tripped[1].Reason)
+		assert.Equal([]string{"ipmi_exporter/ctrl02[L4CON]"}, pr.Tripped[1].Entries)
+	}
+
+	var b bytes.Buffer
+	assert.NoError(writeReport(&b, pr.Status, &Report{Proxies: []*ProxyReport{pr}}, nil))
+
+	var report Report
+	if assert.NoError(json.Unmarshal(b.Bytes(), &report)) {
+		assert.Equal("WARNING", report.State)
+		assert.Len(report.Proxies, 1)
+		assert.Empty(report.Processes)
+		assert.Empty(report.Errors)
+	}
+}
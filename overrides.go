@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev2 "github.com/sensu/core/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// proxyOverrides are per-proxy option values from the event metadata by pxname and option path
+var proxyOverrides map[string]map[string]string
+
+// loadProxyOverrides collects <keyspace>/proxy/<pxname>/<option path> annotations and labels.
+// Check metadata wins over entity metadata, annotations win over labels.
+func loadProxyOverrides(event *corev2.Event) map[string]map[string]string {
+	ret := make(map[string]map[string]string)
+	if event == nil {
+		return ret
+	}
+
+	prefix := plugin.Keyspace + "/proxy/"
+	sources := make([]map[string]string, 0, 4)
+	if event.Entity != nil {
+		sources = append(sources, event.Entity.Labels, event.Entity.Annotations)
+	}
+	if event.Check != nil {
+		sources = append(sources, event.Check.Labels, event.Check.Annotations)
+	}
+
+	for _, src := range sources {
+		for key, value := range src {
+			rest, ok := strings.CutPrefix(key, prefix)
+			if !ok {
+				continue
+			}
+
+			pxname, path, ok := cutLast(rest, "/")
+			if !ok || pxname == "" || path == "" {
+				continue
+			}
+
+			if ret[pxname] == nil {
+				ret[pxname] = make(map[string]string)
+			}
+			ret[pxname][strings.ToLower(path)] = value
+		}
+	}
+
+	return ret
+}
+
+// cutLast slices s around the last sep, proxy names may contain slashes
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+
+	return s, "", false
+}
+
+// applyOverrides sets Thresholds from option path values, unknown paths are errors
+func applyOverrides(th *Thresholds, values map[string]string) error {
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, path := range paths {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: path},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: values[path]},
+		)
+	}
+
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(th); err != nil {
+		return err
+	}
+
+	_, okSet := values["missing_ok"]
+	_, failSet := values["missing_fail"]
+	return th.setMissing(okSet, failSet)
+}
+
+// overriddenThresholds applies per-proxy values over Thresholds of the matching rule
+func overriddenThresholds(pxname string, values map[string]string) (*Thresholds, error) {
+	th := ruleThresholds(pxname)
+	if len(values) == 0 {
+		return th, nil
+	}
+
+	ret := *th
+	if err := applyOverrides(&ret, values); err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+// validateProxyOverrides checks that all per-proxy values can be applied over --rules
+func validateProxyOverrides(overrides map[string]map[string]string) error {
+	for pxname, values := range overrides {
+		th, err := overriddenThresholds(pxname, values)
+		if err == nil {
+			err = th.validateChoices()
+		}
+		if err != nil {
+			return fmt.Errorf("%s/proxy/%s: %w", plugin.Keyspace, pxname, err)
+		}
+	}
+
+	return nil
+}
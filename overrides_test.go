@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+
+	corev2 "github.com/sensu/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyOverrides(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	defer func() { proxyOverrides, rules = nil, nil }()
+
+	plugin = Config{
+		PluginConfig: sensu.PluginConfig{Keyspace: "sensu.io/plugins/sensu-go-haproxy-check/config"},
+		Thresholds:   Thresholds{WarningPercent: 50, CriticalPercent: 25},
+	}
+
+	event := corev2.FixtureEvent("entity1", "haproxy")
+	event.Entity.Annotations = map[string]string{
+		"sensu.io/plugins/sensu-go-haproxy-check/config/proxy/ipmi_exporter/critical_percent": "90",
+		"sensu.io/plugins/sensu-go-haproxy-check/config/proxy/ipmi_exporter/warning_percent":  "95",
+		"sensu.io/plugins/sensu-go-haproxy-check/config/critical_percent":                     "10",
+	}
+	event.Entity.Labels = map[string]string{
+		"sensu.io/plugins/sensu-go-haproxy-check/config/proxy/bk/api/min_critical_count": "2",
+	}
+	event.Check.Annotations = map[string]string{
+		"sensu.io/plugins/sensu-go-haproxy-check/config/proxy/ipmi_exporter/warning_percent": "99",
+	}
+
+	proxyOverrides = loadProxyOverrides(event)
+	assert.Equal(map[string]map[string]string{
+		"ipmi_exporter": {"critical_percent": "90", "warning_percent": "99"},
+		"bk/api":        {"min_critical_count": "2"},
+	}, proxyOverrides)
+	assert.NoError(validateProxyOverrides(proxyOverrides))
+
+	th := thresholdsFor("ipmi_exporter")
+	assert.Equal(float32(99), th.WarningPercent)
+	assert.Equal(float32(90), th.CriticalPercent)
+	assert.Equal(2, thresholdsFor("bk/api").MinCriticalCount)
+	assert.Equal(float32(50), thresholdsFor("other").WarningPercent)
+	assert.Equal(float32(50), plugin.WarningPercent)
+
+	// overrides are applied over rules
+	rules = []Rule{{Name: "ipmi_exporter", Thresholds: Thresholds{MinWarningCount: 3}}}
+	th = thresholdsFor("ipmi_exporter")
+	assert.Equal(3, th.MinWarningCount)
+	assert.Equal(float32(99), th.WarningPercent)
+
+	stats := testingStats(t)
+	status, err := checkService("ipmi_exporter", stats["ipmi_exporter"])
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+
+	proxyOverrides["ipmi_exporter"]["min_critical_count"] = "4"
+	status, err = checkService("ipmi_exporter", stats["ipmi_exporter"])
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateCritical, status)
+
+	th = &Thresholds{MissingOk: true}
+	if assert.NoError(applyOverrides(th, map[string]string{"missing_fail": "true"})) {
+		assert.True(th.MissingFail)
+		assert.False(th.MissingOk)
+	}
+	assert.Error(validateProxyOverrides(map[string]map[string]string{"px": {"missing_ok": "true", "missing_fail": "true"}}))
+
+	// validated over the matching rule
+	rules = []Rule{{Name: "px", Thresholds: Thresholds{MissingFail: true}}}
+	assert.NoError(validateProxyOverrides(map[string]map[string]string{"px": {"missing_ok": "true"}}))
+	assert.Error(validateProxyOverrides(map[string]map[string]string{"px": {"flap_severity": "fatal"}}))
+
+	proxyOverrides = map[string]map[string]string{"px": {"missing_ok": "true", "missing_fail": "true"}}
+	output := captureLog(func() { th = thresholdsFor("px") })
+	assert.Contains(output, "Failed to apply sensu.io/plugins/sensu-go-haproxy-check/config/proxy/px overrides: ")
+	assert.True(th.MissingFail)
+	assert.False(th.MissingOk)
+
+	assert.Error(validateProxyOverrides(map[string]map[string]string{"px": {"no_such_option": "1"}}))
+	assert.Error(validateProxyOverrides(map[string]map[string]string{"px": {"warning_percent": "abc"}}))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// checkPeers checks peers synchronization of all instances
+func checkPeers() (int, error) {
+	return checkInstances("peers",
+		func(_ *Instance, e haproxy.Executor) ([]haproxy.PeersSection, []byte, error) {
+			return haproxy.GetPeers(e)
+		},
+		func(_ *Instance, _ haproxy.Executor, sections []haproxy.PeersSection) (int, error) {
+			return checkPeersSections(sections), nil
+		})
+}
+
+// checkPeersSections reports remote peers which are not connected with --peer-status-severity
+// and sections which did not finish initial resynchronization with --peer-resync-severity
+func checkPeersSections(sections []haproxy.PeersSection) int {
+	disconnected := make([]string, 0)
+	resync := make([]string, 0)
+	peers := 0
+	for _, s := range sections {
+		for _, p := range s.Peers {
+			if p.Local {
+				continue
+			}
+			peers++
+
+			if p.Status != haproxy.PeerStatusEstablished {
+				disconnected = append(disconnected, fmt.Sprintf("%s/%s (%s): %s, reconnect %s", s.ID, p.Name, p.Addr, p.Status, p.Reconnect))
+			}
+		}
+
+		if !s.ResyncDone() {
+			resync = append(resync, fmt.Sprintf("%s: flags 0x%x, resync timeout %s", s.ID, s.Flags, s.ResyncTimeout))
+		}
+	}
+
+	log.Printf("Peers sections: %d, remote peers: %d", len(sections), peers)
+
+	return max(
+		logGroup("Peers not connected", severityState(plugin.PeerStatusSeverity), disconnected),
+		logGroup("Peers resync not finished", severityState(plugin.PeerResyncSeverity), resync),
+	)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+func TestCheckPeersSections(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{PeerStatusSeverity: SeverityCritical, PeerResyncSeverity: SeverityWarning}
+
+	sections := []haproxy.PeersSection{{
+		ID:    "mypeers",
+		Flags: haproxy.PeersResyncLocal | haproxy.PeersResyncRemote,
+		Peers: []haproxy.Peer{
+			{Name: "hap1", Local: true, Status: "NONE"},
+			{Name: "hap2", Status: haproxy.PeerStatusEstablished},
+		},
+	}}
+	assert.Equal(sensu.CheckStateOK, checkPeersSections(sections))
+	assert.Equal(sensu.CheckStateOK, checkPeersSections(nil))
+
+	sections[0].Flags = haproxy.PeersResyncLocal
+	assert.Equal(sensu.CheckStateWarning, checkPeersSections(sections))
+
+	sections[0].Peers[1] = haproxy.Peer{Name: "hap2", Addr: "10.0.0.2:10000", Status: "CONN", Reconnect: "3s"}
+	output := captureLog(func() { assert.Equal(sensu.CheckStateCritical, checkPeersSections(sections)) })
+	assert.Contains(output, "\tmypeers/hap2 (10.0.0.2:10000): CONN, reconnect 3s\n")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// Server policies, how servers in special states count toward up percent and min counts
+const (
+	PolicyUp     = "up"
+	PolicyDown   = "down"
+	PolicyIgnore = "ignore"
+	// PolicyState counts "UP n/m" as UP and "DOWN n/m" as DOWN
+	PolicyState = "state"
+	// PolicyCount counts backup servers by their state
+	PolicyCount = "count"
+)
+
+// validateChoices checks policies and severities set by --rules or event metadata, empty value is the default one
+func (th *Thresholds) validateChoices() error {
+	for _, p := range []struct {
+		Name  string
+		Value string
+		Allow []string
+	}{
+		{"maint_policy", th.MaintPolicy, []string{PolicyUp, PolicyDown, PolicyIgnore}},
+		{"drain_policy", th.DrainPolicy, []string{PolicyUp, PolicyDown, PolicyIgnore}},
+		{"nolb_policy", th.NolbPolicy, []string{PolicyUp, PolicyDown, PolicyIgnore}},
+		{"transitional_policy", th.TransitionalPolicy, []string{PolicyState, PolicyUp, PolicyDown, PolicyIgnore}},
+		{"backup_policy", th.BackupPolicy, []string{PolicyCount, PolicyIgnore}},
+		{"flap_severity", th.FlapSeverity, []string{SeverityWarning, SeverityCritical}},
+	} {
+		if p.Value != "" && !slices.Contains(p.Allow, p.Value) {
+			return fmt.Errorf("%s: unsupported value: %s", p.Name, p.Value)
+		}
+	}
+
+	return nil
+}
+
+func policyOr(policy, def string) string {
+	if policy == "" {
+		return def
+	}
+
+	return policy
+}
+
+// serverPolicy returns how the server counts toward availability: up, down or ignore.
+// Servers in regular states count as StatLine.IsUp says.
+func serverPolicy(s haproxy.StatLine, backend *haproxy.StatLine, th *Thresholds) string {
+	if s.IsBackup() && th.BackupPolicy == PolicyIgnore {
+		return PolicyIgnore
+	}
+
+	status, transitional := s.BaseStatus()
+	if transitional {
+		switch policy := policyOr(th.TransitionalPolicy, PolicyDown); policy {
+		case PolicyState:
+			s.Status = status
+		default:
+			return policy
+		}
+	}
+
+	switch status {
+	case "MAINT":
+		return policyOr(th.MaintPolicy, PolicyDown)
+	case "DRAIN":
+		return policyOr(th.DrainPolicy, PolicyUp)
+	case "NOLB":
+		return policyOr(th.NolbPolicy, PolicyDown)
+	}
+
+	if s.IsUp(backend) {
+		return PolicyUp
+	}
+
+	return PolicyDown
+}
+
+// ignoredName makes a name of the server excluded from availability for check logs
+func ignoredName(s haproxy.StatLine) string {
+	if s.IsBackup() {
+		return fmt.Sprintf("%s (backup, %s)", s.LogName(), s.Status)
+	}
+
+	return fmt.Sprintf("%s (%s)", s.LogName(), s.Status)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+func TestServerPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	th := &Thresholds{}
+	for status, expected := range map[string]string{
+		"UP":                  PolicyUp,
+		"DOWN":                PolicyDown,
+		"no check":            PolicyUp,
+		"MAINT":               PolicyDown,
+		"MAINT (via bk/srv1)": PolicyDown,
+		"DRAIN":               PolicyUp,
+		"NOLB":                PolicyDown,
+		"UP 1/3":              PolicyDown,
+		"DOWN 1/2":            PolicyDown,
+	} {
+		assert.Equal(expected, serverPolicy(haproxy.StatLine{Status: status}, nil, th), status)
+	}
+
+	th = &Thresholds{MaintPolicy: PolicyIgnore, DrainPolicy: PolicyDown, NolbPolicy: PolicyUp, TransitionalPolicy: PolicyState}
+	for status, expected := range map[string]string{
+		"MAINT (resolution)": PolicyIgnore,
+		"DRAIN":              PolicyDown,
+		"NOLB":               PolicyUp,
+		"UP 1/3":             PolicyUp,
+		"DOWN 1/2":           PolicyDown,
+		"NOLB 1/2":           PolicyUp,
+	} {
+		assert.Equal(expected, serverPolicy(haproxy.StatLine{Status: status}, nil, th), status)
+	}
+
+	backup := haproxy.StatLine{Status: "UP", Bck: haproxy.NewInt64(1)}
+	assert.Equal(PolicyUp, serverPolicy(backup, nil, th))
+	th.BackupPolicy = PolicyIgnore
+	assert.Equal(PolicyIgnore, serverPolicy(backup, nil, th))
+
+	assert.NoError(th.validateChoices())
+	th.BackupPolicy = PolicyUp
+	assert.Error(th.validateChoices())
+}
+
+func TestCheckServersPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{Thresholds: Thresholds{WarningPercent: 100, CriticalPercent: 50}}
+
+	svc := testingStats(t)["ipmi_exporter"]
+	srv := svc["ctrl02"]
+	srv.Status = "MAINT"
+	svc["ctrl02"] = srv
+
+	pr, err := checkProxy("", "ipmi_exporter", svc)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateWarning, pr.Status)
+	assert.Len(pr.Failed, 1)
+
+	plugin.MaintPolicy = PolicyIgnore
+	pr, err = checkProxy("", "ipmi_exporter", svc)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, pr.Status)
+	assert.Equal(2, pr.Servers)
+	assert.Empty(pr.Failed)
+	if assert.Len(pr.Ignored, 1) {
+		assert.Equal("ctrl02", pr.Ignored[0].Svname)
+		assert.Equal("MAINT", pr.Ignored[0].Status)
+	}
+
+	// everything in maintenance is not a failure
+	for _, name := range []string{"ctrl01", "ctrl03"} {
+		srv := svc[name]
+		srv.Status = "MAINT"
+		svc[name] = srv
+	}
+	pr, err = checkProxy("", "ipmi_exporter", svc)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, pr.Status)
+	assert.Equal(0, pr.Servers)
+
+	plugin.MinCriticalCount = 1
+	status, err := checkService("ipmi_exporter", svc)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateCritical, status)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	corev2 "github.com/sensu/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"go.uber.org/multierr"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// resolversData is fetched from an instance for --mode resolvers
+type resolversData struct {
+	Sections []haproxy.ResolversSection
+	Stats    haproxy.Stats
+}
+
+// getResolvers query resolvers sections and stats of servers.
+// Stats of all workers are aggregated with --master, like in stats mode.
+func getResolvers(in *Instance, e haproxy.Executor) (resolversData, []byte, error) {
+	var r resolversData
+	var rawData, rawStats []byte
+	var err error
+	r.Sections, rawData, err = haproxy.GetResolvers(e)
+	if err != nil {
+		return r, nil, err
+	}
+
+	format := haproxy.StatFormat(plugin.StatFormat)
+	if plugin.Master {
+		r.Stats, rawStats, err = haproxy.GetMasterStats(in.Dialer, format)
+	} else {
+		r.Stats, rawStats, err = haproxy.GetStats(in.Dialer, format)
+	}
+	if err != nil {
+		return r, nil, fmt.Errorf("stats: %w", err)
+	}
+
+	return r, append(rawData, rawStats...), nil
+}
+
+// checkResolvers checks DNS nameservers of all instances
+// and servers which went to maintenance because their names do not resolve
+func checkResolvers(event *corev2.Event) (int, error) {
+	var err error
+	state, err = LoadState(stateFilePath(plugin.StateDir, sourceName(), checkName(event)), time.Now())
+	if err != nil {
+		return sensu.CheckStateUnknown, err
+	}
+
+	ret, err := checkInstances("resolvers", getResolvers,
+		func(in *Instance, _ haproxy.Executor, r resolversData) (int, error) {
+			state.Prefix = in.statePrefix()
+			return max(checkResolversSections(r.Sections), checkResolution(r.Stats)), nil
+		})
+
+	if state.Resets > 0 {
+		log.Printf("Counters reset for %d entries, HAProxy was reloaded", state.Resets)
+	}
+
+	return ret, multierr.Append(err, state.Save())
+}
+
+// checkResolversSections compares failed queries of nameservers since the previous run
+// with --resolvers-warning-percent and --resolvers-critical-percent
+func checkResolversSections(sections []haproxy.ResolversSection) int {
+	critical := make([]string, 0)
+	warning := make([]string, 0)
+	nameservers := 0
+	for _, s := range sections {
+		for _, ns := range s.Nameservers {
+			nameservers++
+
+			deltas, ok := state.Deltas("dns/"+s.Name+"/"+ns.Name, Counters(ns.Counters))
+			if !ok {
+				continue
+			}
+
+			sent := deltas["sent"]
+			if sent == 0 || sent < int64(plugin.ResolversMinSent) {
+				continue
+			}
+
+			failed := haproxy.Nameserver{Counters: deltas}.Errors()
+			pct := float32(failed) * 100 / float32(sent)
+			desc := fmt.Sprintf("%s/%s: %d of %d (%.0f%%) queries failed", s.Name, ns.Name, failed, sent, pct)
+			if details := errorDetails(deltas); details != "" {
+				desc += ": " + details
+			}
+
+			switch thresholdState(pct, plugin.ResolversWarningPercent, plugin.ResolversCriticalPercent) {
+			case sensu.CheckStateCritical:
+				critical = append(critical, desc)
+			case sensu.CheckStateWarning:
+				warning = append(warning, desc)
+			}
+		}
+	}
+
+	log.Printf("Resolvers sections: %d, nameservers: %d", len(sections), nameservers)
+
+	return max(
+		logGroup("Resolvers errors", sensu.CheckStateCritical, critical),
+		logGroup("Resolvers errors", sensu.CheckStateWarning, warning),
+	)
+}
+
+// errorDetails lists non-zero error counters, e.g. "timeout 3, nx 1"
+func errorDetails(counters Counters) string {
+	details := make([]string, 0)
+	for _, name := range haproxy.ResolverErrorCounters {
+		if v := counters[name]; v > 0 {
+			details = append(details, fmt.Sprintf("%s %d", name, v))
+		}
+	}
+
+	return strings.Join(details, ", ")
+}
+
+// isResolutionMaint reports if the server is in maintenance because its name does not resolve
+func isResolutionMaint(s haproxy.StatLine) bool {
+	status, _ := s.BaseStatus()
+	return status == "MAINT" && strings.Contains(s.Status, "resolution")
+}
+
+// checkResolution reports servers in MAINT (resolution).
+// Unused server-template slots stay there normally, so only backends without
+// any resolved server, or with less than --resolution-min-resolved of them,
+// change the state with --resolution-severity.
+func checkResolution(stats haproxy.Stats) int {
+	pxnames := make([]string, 0, len(stats))
+	for pxname := range stats {
+		if proxyMatches(pxname) {
+			pxnames = append(pxnames, pxname)
+		}
+	}
+	sort.Strings(pxnames)
+
+	minResolved := max(plugin.ResolutionMinResolved, 1)
+	failed := make([]string, 0)
+	partial := make([]string, 0)
+	for _, pxname := range pxnames {
+		servers := filterServers(stats[pxname]).Servers()
+
+		names := make([]string, 0)
+		for _, s := range servers.Lines() {
+			if isResolutionMaint(s) {
+				names = append(names, s.Svname)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		desc := fmt.Sprintf("%s: %d of %d servers: %s", pxname, len(names), len(servers), strings.Join(names, ", "))
+		if len(servers)-len(names) < minResolved {
+			failed = append(failed, desc)
+		} else {
+			partial = append(partial, desc)
+		}
+	}
+
+	if len(partial) > 0 {
+		log.Printf("Servers in MAINT (resolution), backends with %d or more resolved:", minResolved)
+		for _, desc := range partial {
+			log.Printf("\t%s", desc)
+		}
+	}
+
+	return logGroup("Servers in MAINT (resolution)", severityState(plugin.ResolutionSeverity), failed)
+}
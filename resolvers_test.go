@@ -0,0 +1,91 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+func TestCheckResolversSections(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{ResolversWarningPercent: 10, ResolversCriticalPercent: 50, ResolversMinSent: 10}
+
+	defer func(saved *StateStore) { state = saved }(state)
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Unix(1700000000, 0)
+
+	var err error
+	state, err = LoadState(path, now)
+	if !assert.NoError(err) {
+		return
+	}
+
+	sections := []haproxy.ResolversSection{{
+		Name: "mydns",
+		Nameservers: []haproxy.Nameserver{
+			{Name: "dns1", Counters: map[string]int64{"sent": 100, "valid": 100, "timeout": 0, "nx": 0}},
+			{Name: "dns2", Counters: map[string]int64{"sent": 100, "valid": 100, "timeout": 0, "nx": 0}},
+		},
+	}}
+
+	// first run has nothing to compare with
+	assert.Equal(sensu.CheckStateOK, checkResolversSections(sections))
+	assert.NoError(state.Save())
+
+	state, err = LoadState(path, now.Add(time.Minute))
+	if !assert.NoError(err) {
+		return
+	}
+
+	sections[0].Nameservers[0].Counters = map[string]int64{"sent": 120, "valid": 116, "timeout": 3, "nx": 1}
+	sections[0].Nameservers[1].Counters = map[string]int64{"sent": 105, "valid": 100, "timeout": 5, "nx": 0}
+
+	output := captureLog(func() { assert.Equal(sensu.CheckStateWarning, checkResolversSections(sections)) })
+	assert.Contains(output, "Resolvers errors warning:\n")
+	assert.Contains(output, "\tmydns/dns1: 4 of 20 (20%) queries failed: nx 1, timeout 3\n")
+	// dns2 sent less than --resolvers-min-sent
+	assert.NotContains(output, "dns2")
+}
+
+func TestCheckResolution(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{ResolutionSeverity: SeverityCritical}
+
+	stats := haproxy.Stats{
+		"be_app": haproxy.StatService{
+			"BACKEND": {Pxname: "be_app", Svname: "BACKEND", Status: "UP"},
+			"app1":    {Pxname: "be_app", Svname: "app1", Status: "UP"},
+			"app2":    {Pxname: "be_app", Svname: "app2", Status: "MAINT (resolution)"},
+		},
+	}
+	output := captureLog(func() { assert.Equal(sensu.CheckStateOK, checkResolution(stats)) })
+	assert.Contains(output, "Servers in MAINT (resolution), backends with 1 or more resolved:\n\tbe_app: 1 of 2 servers: app2\n")
+
+	plugin.ResolutionMinResolved = 2
+	output = captureLog(func() { assert.Equal(sensu.CheckStateCritical, checkResolution(stats)) })
+	assert.Contains(output, "Servers in MAINT (resolution) critical:\n\tbe_app: 1 of 2 servers: app2\n")
+	plugin.ResolutionMinResolved = 0
+
+	stats["be_api"] = haproxy.StatService{
+		"api1": {Pxname: "be_api", Svname: "api1", Status: "MAINT (resolution)"},
+		"api2": {Pxname: "be_api", Svname: "api2", Status: "MAINT (resolution)"},
+	}
+	output = captureLog(func() { assert.Equal(sensu.CheckStateCritical, checkResolution(stats)) })
+	assert.Contains(output, "Servers in MAINT (resolution) critical:\n\tbe_api: 2 of 2 servers: api1, api2\n")
+
+	// MAINT set by the operator is not a resolution failure
+	stats["be_api"] = haproxy.StatService{
+		"api1": {Pxname: "be_api", Svname: "api1", Status: "MAINT"},
+	}
+	assert.Equal(sensu.CheckStateOK, checkResolution(stats))
+}
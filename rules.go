@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Thresholds are per-service limits, which can be overridden by --rules
+type Thresholds struct {
+	MissingOk                      bool    `yaml:"missing_ok"`
+	MissingFail                    bool    `yaml:"missing_fail"`
+	WarningPercent                 float32 `yaml:"warning_percent"`
+	CriticalPercent                float32 `yaml:"critical_percent"`
+	MinWarningCount                int     `yaml:"min_warning_count"`
+	MinCriticalCount               int     `yaml:"min_critical_count"`
+	SessionWarningPercent          float32 `yaml:"session_warning_percent"`
+	SessionCriticalPercent         float32 `yaml:"session_critical_percent"`
+	FrontendSessionWarningPercent  float32 `yaml:"frontend_session_warning_percent"`
+	FrontendSessionCriticalPercent float32 `yaml:"frontend_session_critical_percent"`
+	BackendSessionWarningPercent   float32 `yaml:"backend_session_warning_percent"`
+	BackendSessionCriticalPercent  float32 `yaml:"backend_session_critical_percent"`
+	QueueWarning                   int     `yaml:"queue_warning"`
+	QueueCritical                  int     `yaml:"queue_critical"`
+	QueueWarningPercent            float32 `yaml:"queue_warning_percent"`
+	QueueCriticalPercent           float32 `yaml:"queue_critical_percent"`
+	QueueTimeWarning               int     `yaml:"queue_time_warning"`
+	QueueTimeCritical              int     `yaml:"queue_time_critical"`
+	ConnectTimeWarning             int     `yaml:"connect_time_warning"`
+	ConnectTimeCritical            int     `yaml:"connect_time_critical"`
+	ResponseTimeWarning            int     `yaml:"response_time_warning"`
+	ResponseTimeCritical           int     `yaml:"response_time_critical"`
+	TotalTimeWarning               int     `yaml:"total_time_warning"`
+	TotalTimeCritical              int     `yaml:"total_time_critical"`
+	HTTP5xxWarningPercent          float32 `yaml:"http_5xx_warning_percent"`
+	HTTP5xxCriticalPercent         float32 `yaml:"http_5xx_critical_percent"`
+	HTTP5xxRateWarning             float32 `yaml:"http_5xx_rate_warning"`
+	HTTP5xxRateCritical            float32 `yaml:"http_5xx_rate_critical"`
+	HTTP4xxWarningPercent          float32 `yaml:"http_4xx_warning_percent"`
+	HTTP4xxCriticalPercent         float32 `yaml:"http_4xx_critical_percent"`
+	HTTP4xxRateWarning             float32 `yaml:"http_4xx_rate_warning"`
+	HTTP4xxRateCritical            float32 `yaml:"http_4xx_rate_critical"`
+	HTTPMinResponses               int     `yaml:"http_min_responses"`
+	FlapCount                      int     `yaml:"flap_count"`
+	FlapFailCount                  int     `yaml:"flap_fail_count"`
+	FlapWindow                     int     `yaml:"flap_window"`
+	FlapSeverity                   string  `yaml:"flap_severity"`
+	MaintPolicy                    string  `yaml:"maint_policy"`
+	DrainPolicy                    string  `yaml:"drain_policy"`
+	NolbPolicy                     string  `yaml:"nolb_policy"`
+	TransitionalPolicy             string  `yaml:"transitional_policy"`
+	BackupPolicy                   string  `yaml:"backup_policy"`
+}
+
+// Rule overrides Thresholds for proxies matching Name or Pattern
+type Rule struct {
+	Name       string
+	Pattern    *regexp.Regexp
+	Thresholds Thresholds
+}
+
+// ruleSpec is a --rules file entry, thresholds not set in the entry keep command line values
+type ruleSpec struct {
+	Name       string `yaml:"name"`
+	Pattern    string `yaml:"pattern"`
+	Thresholds `yaml:",inline"`
+}
+
+var rules []Rule
+
+// LoadRules reads --rules YAML or JSON file:
+//
+//	rules:
+//	  - name: bk_admin
+//	    critical_percent: 50
+//	    min_critical_count: 1
+//	  - pattern: ^bk_api_
+//	    warning_percent: 90
+//	    min_warning_count: 30
+func LoadRules(path string, defaults Thresholds) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("rules read error: %w", err)
+	}
+
+	// strict pass to report unknown keys and wrong types
+	var specs struct {
+		Rules []ruleSpec `yaml:"rules"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err = dec.Decode(&specs)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("rules parse error: %w", err)
+	}
+
+	// second pass to apply set values over the defaults
+	var nodes struct {
+		Rules []yaml.Node `yaml:"rules"`
+	}
+	err = yaml.Unmarshal(data, &nodes)
+	if err != nil {
+		return nil, fmt.Errorf("rules parse error: %w", err)
+	}
+
+	ret := make([]Rule, 0, len(specs.Rules))
+	names := make(map[string]bool)
+	for i, spec := range specs.Rules {
+		r := Rule{
+			Name:       spec.Name,
+			Thresholds: defaults,
+		}
+
+		switch {
+		case spec.Name != "" && spec.Pattern != "":
+			return nil, fmt.Errorf("rule %d: only one name or pattern should be used", i+1)
+		case spec.Name != "":
+			if names[spec.Name] {
+				return nil, fmt.Errorf("rule %d: duplicate name: %s", i+1, spec.Name)
+			}
+			names[spec.Name] = true
+		case spec.Pattern != "":
+			r.Pattern, err = regexp.Compile(spec.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: pattern error: %w", i+1, err)
+			}
+		default:
+			return nil, fmt.Errorf("rule %d: name or pattern is required", i+1)
+		}
+
+		err = nodes.Rules[i].Decode(&r.Thresholds)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+
+		keys := nodeKeys(&nodes.Rules[i])
+		if err := r.Thresholds.setMissing(keys["missing_ok"], keys["missing_fail"]); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+
+		if err := r.Thresholds.validateChoices(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+
+		ret = append(ret, r)
+	}
+
+	return ret, nil
+}
+
+// nodeKeys returns keys set in the YAML mapping
+func nodeKeys(node *yaml.Node) map[string]bool {
+	ret := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		ret[node.Content[i].Value] = true
+	}
+
+	return ret
+}
+
+// setMissing resolves missing_ok and missing_fail after a rule or an override is applied over the defaults:
+// the flag set to true by it clears the other one inherited from the defaults.
+func (th *Thresholds) setMissing(okSet, failSet bool) error {
+	switch {
+	case okSet && failSet:
+		if th.MissingOk && th.MissingFail {
+			return fmt.Errorf("only one missing_ok or missing_fail should be used")
+		}
+	case okSet && th.MissingOk:
+		th.MissingFail = false
+	case failSet && th.MissingFail:
+		th.MissingOk = false
+	}
+
+	return nil
+}
+
+// thresholdsFor returns Thresholds for the proxy with its event metadata overrides applied.
+// Overrides are validated by checkArgs, rule Thresholds are used if they still fail.
+func thresholdsFor(pxname string) *Thresholds {
+	th, err := overriddenThresholds(pxname, proxyOverrides[pxname])
+	if err != nil {
+		log.Printf("Failed to apply %s/proxy/%s overrides: %v", plugin.Keyspace, pxname, err)
+		return ruleThresholds(pxname)
+	}
+
+	return th
+}
+
+// ruleThresholds returns Thresholds of the most specific rule for the proxy:
+// the rule with the same name, then the matching rule with the longest pattern.
+// Command line Thresholds are used if no rule matches.
+func ruleThresholds(pxname string) *Thresholds {
+	var best *Rule
+	for i := range rules {
+		r := &rules[i]
+		if r.Pattern == nil {
+			if r.Name == pxname {
+				return &r.Thresholds
+			}
+			continue
+		}
+
+		if r.Pattern.MatchString(pxname) && (best == nil || len(r.Pattern.String()) > len(best.Pattern.String())) {
+			best = r
+		}
+	}
+
+	if best != nil {
+		return &best.Thresholds
+	}
+
+	return &plugin.Thresholds
+}
+
+// stateChecksEnabled reports if any threshold over counter deltas is set, so the state store is needed
+func stateChecksEnabled() bool {
+	if plugin.Thresholds.stateChecksEnabled() {
+		return true
+	}
+
+	for _, r := range rules {
+		if r.Thresholds.stateChecksEnabled() {
+			return true
+		}
+	}
+
+	for pxname := range proxyOverrides {
+		if thresholdsFor(pxname).stateChecksEnabled() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeRules(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadRules(t *testing.T) {
+	assert := assert.New(t)
+
+	defaults := Thresholds{WarningPercent: 50, CriticalPercent: 25, MinWarningCount: 2}
+
+	path := writeRules(t, `
+rules:
+  - name: ipmi_exporter
+    critical_percent: 90
+    missing_fail: true
+  - pattern: ^bk_
+    warning_percent: 75
+  - pattern: ^bk_dashboard_
+    min_warning_count: 5
+`)
+	r, err := LoadRules(path, defaults)
+	if !assert.NoError(err) {
+		return
+	}
+	if !assert.Len(r, 3) {
+		return
+	}
+
+	assert.Equal("ipmi_exporter", r[0].Name)
+	assert.Equal(Thresholds{WarningPercent: 50, CriticalPercent: 90, MinWarningCount: 2, MissingFail: true}, r[0].Thresholds)
+	assert.Equal(Thresholds{WarningPercent: 75, CriticalPercent: 25, MinWarningCount: 2}, r[1].Thresholds)
+
+	// JSON is YAML too
+	path = writeRules(t, `{"rules": [{"pattern": "^bk_", "min_critical_count": 3}]}`)
+	r, err = LoadRules(path, defaults)
+	if assert.NoError(err) && assert.Len(r, 1) {
+		assert.Equal(3, r[0].Thresholds.MinCriticalCount)
+	}
+
+	// the missing flag of the rule replaces the one from the command line
+	path = writeRules(t, "rules:\n  - name: x\n    missing_ok: true\n  - name: y\n    min_warning_count: 1\n")
+	r, err = LoadRules(path, Thresholds{MissingFail: true})
+	if assert.NoError(err) && assert.Len(r, 2) {
+		assert.True(r[0].Thresholds.MissingOk)
+		assert.False(r[0].Thresholds.MissingFail)
+		assert.True(r[1].Thresholds.MissingFail)
+	}
+
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{"unknown key", "rules:\n  - name: x\n    warning_pct: 10\n"},
+		{"wrong type", "rules:\n  - name: x\n    warning_percent: abc\n"},
+		{"no match", "rules:\n  - warning_percent: 10\n"},
+		{"name and pattern", "rules:\n  - name: x\n    pattern: y\n"},
+		{"bad pattern", "rules:\n  - pattern: (\n"},
+		{"duplicate", "rules:\n  - name: x\n  - name: x\n"},
+		{"missing", "rules:\n  - name: x\n    missing_ok: true\n    missing_fail: true\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadRules(writeRules(t, tc.content), defaults)
+			assert.Error(err)
+		})
+	}
+
+	_, err = LoadRules(filepath.Join(t.TempDir(), "missing.yaml"), defaults)
+	assert.Error(err)
+}
+
+func TestThresholdsFor(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	defer func() { rules = nil }()
+
+	plugin = Config{Thresholds: Thresholds{WarningPercent: 50}}
+
+	var err error
+	rules, err = LoadRules(writeRules(t, `
+rules:
+  - pattern: ^bk_
+    warning_percent: 60
+  - pattern: ^bk_dashboard_
+    warning_percent: 70
+  - name: bk_dashboard_cluster
+    warning_percent: 80
+`), plugin.Thresholds)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(float32(80), thresholdsFor("bk_dashboard_cluster").WarningPercent)
+	assert.Equal(float32(70), thresholdsFor("bk_dashboard_other").WarningPercent)
+	assert.Equal(float32(60), thresholdsFor("bk_api").WarningPercent)
+	assert.Equal(float32(50), thresholdsFor("ipmi_exporter").WarningPercent)
+
+	// rules are used by checkService
+	rules, err = LoadRules(writeRules(t, `
+rules:
+  - name: ipmi_exporter
+    min_critical_count: 4
+`), Thresholds{})
+	if !assert.NoError(err) {
+		return
+	}
+
+	stats := testingStats(t)
+	status, err := checkService("ipmi_exporter", stats["ipmi_exporter"])
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateCritical, status)
+
+	status, err = checkService("bk_dashboard_cluster", stats["bk_dashboard_cluster"])
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateOK, status)
+}
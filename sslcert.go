@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// checkSSLCerts checks expiry of certificates loaded by all instances
+// and reports uncommitted "set ssl cert" transactions
+func checkSSLCerts(now time.Time) (int, error) {
+	return checkInstances("SSL certificates",
+		func(_ *Instance, e haproxy.Executor) ([]haproxy.SSLCert, []byte, error) {
+			return haproxy.GetSSLCerts(e)
+		},
+		func(_ *Instance, _ haproxy.Executor, certs []haproxy.SSLCert) (int, error) {
+			return checkSSLCertList(certs, now), nil
+		})
+}
+
+// expiryDays returns whole days left before the certificate expires, negative if expired
+func expiryDays(cert haproxy.SSLCert, now time.Time) int {
+	return int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24))
+}
+
+// checkSSLCertList compares days before expiry with --ssl-cert-warning-days and --ssl-cert-critical-days
+func checkSSLCertList(certs []haproxy.SSLCert, now time.Time) int {
+	critical := make([]string, 0)
+	warning := make([]string, 0)
+	uncommitted := make([]string, 0)
+	for _, cert := range certs {
+		if cert.Uncommitted {
+			uncommitted = append(uncommitted, cert.Filename)
+		}
+		if cert.NotAfter.IsZero() {
+			continue
+		}
+
+		days := expiryDays(cert, now)
+		var desc string
+		if days < 0 {
+			desc = fmt.Sprintf("%s: %s expired %d days ago (%s)", cert.Filename, cert.Subject, -days, cert.NotAfter.Format(time.DateOnly))
+		} else {
+			desc = fmt.Sprintf("%s: %s expires in %d days (%s)", cert.Filename, cert.Subject, days, cert.NotAfter.Format(time.DateOnly))
+		}
+
+		switch {
+		case plugin.SSLCertCriticalDays > 0 && days < plugin.SSLCertCriticalDays:
+			critical = append(critical, desc)
+		case plugin.SSLCertWarningDays > 0 && days < plugin.SSLCertWarningDays:
+			warning = append(warning, desc)
+		}
+	}
+
+	log.Printf("SSL certificates: %d", len(certs))
+
+	ret := max(
+		logGroup("SSL certificates expiry", sensu.CheckStateCritical, critical),
+		logGroup("SSL certificates expiry", sensu.CheckStateWarning, warning),
+	)
+
+	if len(uncommitted) > 0 {
+		log.Printf("SSL certificate transactions not committed: %s", strings.Join(uncommitted, ", "))
+		ret = max(ret, sensu.CheckStateWarning)
+	}
+
+	return ret
+}
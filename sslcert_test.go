@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+func TestCheckSSLCertList(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{SSLCertWarningDays: 30, SSLCertCriticalDays: 7}
+
+	now := time.Date(2021, time.September, 1, 0, 0, 0, 0, time.UTC)
+	site := haproxy.SSLCert{Filename: "site.pem", Subject: "/CN=example.com", NotAfter: time.Date(2021, time.September, 14, 12, 0, 0, 0, time.UTC)}
+	api := haproxy.SSLCert{Filename: "api.pem", Subject: "/CN=api.example.com", NotAfter: time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+	assert.Equal(13, expiryDays(site, now))
+	assert.Equal(-1, expiryDays(site, now.AddDate(0, 0, 14)))
+
+	assert.Equal(sensu.CheckStateOK, checkSSLCertList([]haproxy.SSLCert{api}, now))
+	assert.Equal(sensu.CheckStateWarning, checkSSLCertList([]haproxy.SSLCert{site, api}, now))
+	assert.Equal(sensu.CheckStateCritical, checkSSLCertList([]haproxy.SSLCert{site, api}, now.AddDate(0, 0, 10)))
+	assert.Equal(sensu.CheckStateCritical, checkSSLCertList([]haproxy.SSLCert{site, api}, now.AddDate(0, 1, 0)))
+
+	// uncommitted transaction
+	api.Uncommitted = true
+	assert.Equal(sensu.CheckStateWarning, checkSSLCertList([]haproxy.SSLCert{api}, now))
+
+	plugin = Config{}
+	assert.Equal(sensu.CheckStateOK, checkSSLCertList([]haproxy.SSLCert{site}, now.AddDate(1, 0, 0)))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Counters is a set of cumulative counters of one object, e.g. hrsp_5xx of a server
+type Counters map[string]int64
+
+// State keeps counters between check runs
+type State struct {
+	Timestamp int64               `json:"timestamp"`
+	Counters  map[string]Counters `json:"counters"`
+}
+
+// StateStore loads the previous run State and collects the current one
+type StateStore struct {
+	Path string
+	// Prefix namespaces keys of the instance being checked
+	Prefix string
+	Now    time.Time
+	Prev   State
+	Next   State
+	// Resets counts objects which counters went backwards
+	Resets int
+}
+
+// stateFilePath makes a state file name unique for the HAProxy source and the check
+func stateFilePath(dir, source, checkName string) string {
+	h := sha1.Sum([]byte(source + "\x00" + checkName)) //nolint:gosec
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.json", plugin.Name, hex.EncodeToString(h[:])[:12]))
+}
+
+// LoadState reads the previous State, missing file is not an error
+func LoadState(path string, now time.Time) (*StateStore, error) {
+	s := &StateStore{
+		Path: path,
+		Now:  now,
+		Next: State{
+			Timestamp: now.Unix(),
+			Counters:  make(map[string]Counters),
+		},
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("state read error: %w", err)
+	}
+
+	err = json.Unmarshal(data, &s.Prev)
+	if err != nil {
+		return nil, fmt.Errorf("state parse error: %w", err)
+	}
+
+	return s, nil
+}
+
+// Save writes the current State atomically
+func (s *StateStore) Save() error {
+	data, err := json.Marshal(&s.Next)
+	if err != nil {
+		return fmt.Errorf("state marshal error: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.Path), 0o755)
+	if err != nil {
+		return fmt.Errorf("state write error: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return fmt.Errorf("state write error: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err2 := tmp.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return fmt.Errorf("state write error: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), s.Path)
+	if err != nil {
+		return fmt.Errorf("state write error: %w", err)
+	}
+
+	return nil
+}
+
+// Elapsed returns time since the previous run
+func (s *StateStore) Elapsed() time.Duration {
+	if s.Prev.Timestamp == 0 {
+		return 0
+	}
+
+	return s.Now.Sub(time.Unix(s.Prev.Timestamp, 0))
+}
+
+// Deltas records current counters of the object and returns increments since the previous run.
+// ok is false on the first run of the object and when any counter went backwards,
+// which means HAProxy was reloaded or counters were cleared.
+func (s *StateStore) Deltas(key string, cur Counters) (deltas Counters, ok bool) {
+	key = s.Prefix + key
+	s.Next.Counters[key] = cur
+
+	prev, found := s.Prev.Counters[key]
+	if !found || s.Elapsed() <= 0 {
+		return nil, false
+	}
+
+	deltas = make(Counters, len(cur))
+	for name, value := range cur {
+		old, found := prev[name]
+		if !found {
+			return nil, false
+		}
+
+		if value < old {
+			s.Resets++
+			return nil, false
+		}
+
+		deltas[name] = value - old
+	}
+
+	return deltas, true
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateStore(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "sub", "state.json")
+	now := time.Unix(1700000000, 0)
+
+	s, err := LoadState(path, now)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(time.Duration(0), s.Elapsed())
+
+	_, ok := s.Deltas("hrsp/px/sv1", Counters{"hrsp_2xx": 10, "hrsp_5xx": 1})
+	assert.False(ok)
+	_, ok = s.Deltas("hrsp/px/sv2", Counters{"hrsp_2xx": 10})
+	assert.False(ok)
+	assert.NoError(s.Save())
+
+	s, err = LoadState(path, now.Add(time.Minute))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(time.Minute, s.Elapsed())
+
+	d, ok := s.Deltas("hrsp/px/sv1", Counters{"hrsp_2xx": 40, "hrsp_5xx": 11})
+	assert.True(ok)
+	assert.Equal(Counters{"hrsp_2xx": 30, "hrsp_5xx": 10}, d)
+
+	// reload
+	_, ok = s.Deltas("hrsp/px/sv2", Counters{"hrsp_2xx": 3})
+	assert.False(ok)
+	assert.Equal(1, s.Resets)
+
+	_, ok = s.Deltas("hrsp/px/sv3", Counters{"hrsp_2xx": 3})
+	assert.False(ok)
+	assert.NoError(s.Save())
+
+	s, err = LoadState(path, now.Add(2*time.Minute))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(s.Prev.Counters, 3)
+	assert.Equal(Counters{"hrsp_2xx": 3}, s.Prev.Counters["hrsp/px/sv2"])
+}
+
+func TestStateFilePath(t *testing.T) {
+	assert := assert.New(t)
+
+	a := stateFilePath("/tmp", "unix:///var/run/haproxy.sock", "haproxy")
+	assert.Equal("/tmp", filepath.Dir(a))
+	assert.Equal(a, stateFilePath("/tmp", "unix:///var/run/haproxy.sock", "haproxy"))
+	assert.NotEqual(a, stateFilePath("/tmp", "unix:///var/run/haproxy.sock", "haproxy-all"))
+	assert.NotEqual(a, stateFilePath("/tmp", "tcp://127.0.0.1:9999", "haproxy"))
+}
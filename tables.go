@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"go.uber.org/multierr"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// checkTables checks usage of stick tables of all instances
+func checkTables() (int, error) {
+	return checkInstances("stick tables",
+		func(_ *Instance, e haproxy.Executor) ([]haproxy.Table, []byte, error) {
+			return haproxy.GetTables(e)
+		},
+		func(_ *Instance, e haproxy.Executor, tables []haproxy.Table) (int, error) {
+			return checkTableList(e, tables)
+		})
+}
+
+// checkTableList compares table usage with --table-warning-percent and --table-critical-percent.
+// Top entries of tables over thresholds are shown with --table-top.
+func checkTableList(e haproxy.Executor, tables []haproxy.Table) (int, error) {
+	// tables are named by their proxies
+	selected := make([]haproxy.Table, 0, len(tables))
+	for _, t := range tables {
+		if proxyMatches(t.Name) {
+			selected = append(selected, t)
+		}
+	}
+
+	log.Printf("Stick tables: %d", len(selected))
+
+	ret := sensu.CheckStateOK
+	var err error
+	for _, t := range selected {
+		pct := t.UsagePercentage()
+		state := thresholdState(pct, plugin.TableWarningPercent, plugin.TableCriticalPercent)
+		if state == sensu.CheckStateOK {
+			continue
+		}
+
+		log.Printf("Stick table %s:", strings.ToLower(stateName(state)))
+		log.Printf("\t%s (%s): %d of %d (%.0f%%) entries", t.Name, t.Type, t.Used, t.Size, pct)
+		ret = max(ret, state)
+
+		if plugin.TableTop > 0 {
+			// only entries with the counter set are sent, not the whole table
+			entries, _, err2 := haproxy.GetTableEntriesOver(e, t.Name, plugin.TableTopCounter, 0)
+			if err2 != nil {
+				err = multierr.Append(err, fmt.Errorf("Failed to get entries of stick table %s: %w", t.Name, err2))
+				continue
+			}
+
+			top := topEntries(entries, plugin.TableTopCounter, plugin.TableTop)
+			log.Printf("\tTop %d of %d entries with %s over 0:", len(top), len(entries), plugin.TableTopCounter)
+			for _, entry := range top {
+				v, _ := entry.Counter(plugin.TableTopCounter)
+				log.Printf("\t\t%s: %d", entry.Key, v)
+			}
+		}
+	}
+
+	return ret, err
+}
+
+// topEntries returns at most n entries with the highest counter, entries without the counter are skipped
+func topEntries(entries []haproxy.TableEntry, counter string, n int) []haproxy.TableEntry {
+	ret := make([]haproxy.TableEntry, 0, len(entries))
+	for _, entry := range entries {
+		if _, ok := entry.Counter(counter); ok {
+			ret = append(ret, entry)
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		a, _ := ret[i].Counter(counter)
+		b, _ := ret[j].Counter(counter)
+		return a > b
+	})
+
+	if len(ret) > n {
+		ret = ret[:n]
+	}
+
+	return ret
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// fakeExecutor answers runtime API commands from the map
+type fakeExecutor map[string]string
+
+func (f fakeExecutor) Exec(cmd string) ([]byte, error) {
+	return []byte(f[cmd]), nil
+}
+
+func TestCheckTableList(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{TableWarningPercent: 80, TableCriticalPercent: 95}
+
+	tables := []haproxy.Table{
+		{Name: "front_pub", Type: "ip", Size: 100, Used: 85},
+		{Name: "back_rdp", Type: "ip", Size: 100, Used: 1},
+	}
+	e := fakeExecutor{
+		"show table front_pub data.http_req_rate gt 0": `# table: front_pub, type: ip, size:100, used:3
+0x1: key=10.0.0.1 use=0 exp=3422 http_req_rate(10000)=3
+0x2: key=10.0.0.2 use=0 exp=9021 http_req_rate(10000)=120
+0x3: key=10.0.0.3 use=0 exp=120 http_req_rate(10000)=40
+`,
+	}
+
+	status, err := checkTableList(e, tables)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateWarning, status)
+
+	plugin.TableTop = 2
+	plugin.TableTopCounter = "http_req_rate"
+	output := captureLog(func() { status, err = checkTableList(e, tables) })
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateWarning, status)
+	assert.Contains(output, "Top 2 of 3 entries with http_req_rate over 0:\n\t\t10.0.0.2: 120\n\t\t10.0.0.3: 40\n")
+
+	// the table does not store the counter
+	plugin.TableTopCounter = "conn_cnt"
+	e["show table front_pub data.conn_cnt gt 0"] = "Data type not stored in this table\n"
+	status, err = checkTableList(e, tables)
+	assert.EqualError(err, "Failed to get entries of stick table front_pub: conn_cnt: data type is not stored in the table")
+	assert.Equal(sensu.CheckStateWarning, status)
+	plugin.TableTop = 0
+
+	tables[0].Used = 99
+	status, _ = checkTableList(e, tables)
+	assert.Equal(sensu.CheckStateCritical, status)
+
+	plugin.ExcludeProxy = []string{"^front_"}
+	assert.NoError(compileFilters())
+	defer func() { excludeProxy = nil }()
+	status, _ = checkTableList(e, tables)
+	assert.Equal(sensu.CheckStateOK, status)
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+// maxOffenders limits how many entries are listed per tripped threshold
+const maxOffenders = 10
+
+// metricThreshold is a limit for a value of stat entries.
+// Max is optional peak value shown next to the offender value.
+// Precision is the number of decimals in the output.
+type metricThreshold struct {
+	Name      string
+	Unit      string
+	Precision int
+	Value     func(l haproxy.StatLine) (float32, bool)
+	Max       func(l haproxy.StatLine) (float32, bool)
+	Warning   float32
+	Critical  float32
+}
+
+// offender is an entry which value is over the threshold
+type offender struct {
+	Line  haproxy.StatLine
+	Value float32
+	State int
+}
+
+func nullValue(n haproxy.NullInt64) (float32, bool) {
+	v, ok := n.Get()
+	return float32(v), ok
+}
+
+// queueThresholds makes thresholds for qcur, qcur/qlimit and qtime
+func queueThresholds(th *Thresholds) []metricThreshold {
+	return []metricThreshold{
+		{
+			Name:     "Queue",
+			Value:    func(l haproxy.StatLine) (float32, bool) { return nullValue(l.Qcur) },
+			Warning:  float32(th.QueueWarning),
+			Critical: float32(th.QueueCritical),
+		},
+		{
+			Name: "Queue limit",
+			Unit: "%",
+			Value: func(l haproxy.StatLine) (float32, bool) {
+				return haproxy.UsagePercentage(l.Qcur, l.Qlimit)
+			},
+			Warning:  th.QueueWarningPercent,
+			Critical: th.QueueCriticalPercent,
+		},
+		{
+			Name:     "Queue time",
+			Unit:     "ms",
+			Value:    func(l haproxy.StatLine) (float32, bool) { return nullValue(l.Qtime) },
+			Max:      func(l haproxy.StatLine) (float32, bool) { return nullValue(l.QtimeMax) },
+			Warning:  float32(th.QueueTimeWarning),
+			Critical: float32(th.QueueTimeCritical),
+		},
+	}
+}
+
+// timeThresholds makes thresholds for average connect, response and total times over last 1024 requests
+func timeThresholds(th *Thresholds) []metricThreshold {
+	return []metricThreshold{
+		{
+			Name:     "Connect time",
+			Unit:     "ms",
+			Value:    func(l haproxy.StatLine) (float32, bool) { return nullValue(l.Ctime) },
+			Max:      func(l haproxy.StatLine) (float32, bool) { return nullValue(l.CtimeMax) },
+			Warning:  float32(th.ConnectTimeWarning),
+			Critical: float32(th.ConnectTimeCritical),
+		},
+		{
+			Name:     "Response time",
+			Unit:     "ms",
+			Value:    func(l haproxy.StatLine) (float32, bool) { return nullValue(l.Rtime) },
+			Max:      func(l haproxy.StatLine) (float32, bool) { return nullValue(l.RtimeMax) },
+			Warning:  float32(th.ResponseTimeWarning),
+			Critical: float32(th.ResponseTimeCritical),
+		},
+		{
+			Name:     "Total time",
+			Unit:     "ms",
+			Value:    func(l haproxy.StatLine) (float32, bool) { return nullValue(l.Ttime) },
+			Max:      func(l haproxy.StatLine) (float32, bool) { return nullValue(l.TtimeMax) },
+			Warning:  float32(th.TotalTimeWarning),
+			Critical: float32(th.TotalTimeCritical),
+		},
+	}
+}
+
+// checkThresholds compares stat entries with thresholds, logs and reports the worst offenders.
+// Entries for which Value is not available are skipped.
+func checkThresholds(pr *ProxyReport, entries haproxy.StatService, thresholds []metricThreshold) int {
+	ret := sensu.CheckStateOK
+	for _, th := range thresholds {
+		if th.Warning <= 0 && th.Critical <= 0 {
+			continue
+		}
+
+		offenders := make([]offender, 0)
+		for _, l := range entries.Lines() {
+			value, ok := th.Value(l)
+			if !ok {
+				continue
+			}
+
+			state := thresholdState(value, th.Warning, th.Critical)
+			if state > sensu.CheckStateOK {
+				offenders = append(offenders, offender{Line: l, Value: value, State: state})
+			}
+		}
+
+		if len(offenders) == 0 {
+			continue
+		}
+
+		sort.SliceStable(offenders, func(i, j int) bool {
+			return offenders[i].Value > offenders[j].Value
+		})
+
+		state := offenders[0].State
+		limit := th.Warning
+		if state == sensu.CheckStateCritical {
+			limit = th.Critical
+		}
+
+		lines := make([]string, 0, min(len(offenders), maxOffenders))
+		log.Printf("%s %s: %d of #%d %s entries", th.Name, strings.ToLower(stateName(state)), len(offenders), len(entries), pr.Pxname)
+		for i, o := range offenders {
+			if i == maxOffenders {
+				log.Printf("\t... and %d more", len(offenders)-maxOffenders)
+				break
+			}
+
+			line := fmt.Sprintf("%s: %.*f%s", o.Line.LogName(), th.Precision, o.Value, th.Unit)
+			if th.Max != nil {
+				if peak, ok := th.Max(o.Line); ok {
+					line += fmt.Sprintf(" (max %.*f%s)", th.Precision, peak, th.Unit)
+				}
+			}
+
+			lines = append(lines, line)
+			log.Printf("\t%s", line)
+		}
+
+		pr.trip(th.Name, state, fmt.Sprintf("%d of #%d entries over %.*f%s", len(offenders), len(entries), th.Precision, limit, th.Unit), lines...)
+		ret = max(ret, state)
+	}
+
+	return ret
+}
+
+// httpResponseCounters are hrsp_* columns, their sum is the number of responses
+var httpResponseCounters = []string{"hrsp_1xx", "hrsp_2xx", "hrsp_3xx", "hrsp_4xx", "hrsp_5xx", "hrsp_other"}
+
+// httpChecksEnabled reports if any HTTP error threshold is set
+func (th *Thresholds) httpChecksEnabled() bool {
+	for _, v := range []float32{
+		th.HTTP5xxWarningPercent, th.HTTP5xxCriticalPercent,
+		th.HTTP5xxRateWarning, th.HTTP5xxRateCritical,
+		th.HTTP4xxWarningPercent, th.HTTP4xxCriticalPercent,
+		th.HTTP4xxRateWarning, th.HTTP4xxRateCritical,
+	} {
+		if v > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stateChecksEnabled reports if any threshold over counter deltas is set
+func (th *Thresholds) stateChecksEnabled() bool {
+	return th.httpChecksEnabled() || th.flapChecksEnabled()
+}
+
+// httpDeltas collects hrsp_* increments since the previous run by svname.
+// Entries without HTTP counters (mode tcp) are skipped.
+func httpDeltas(pxname string, svc haproxy.StatService) map[string]Counters {
+	ret := make(map[string]Counters)
+	if state == nil {
+		return ret
+	}
+
+	for _, l := range svc.Lines() {
+		cur := make(Counters)
+		for i, v := range []haproxy.NullInt64{l.Hrsp1Xx, l.Hrsp2Xx, l.Hrsp3Xx, l.Hrsp4Xx, l.Hrsp5Xx, l.HrspOther} {
+			if v.Valid {
+				cur[httpResponseCounters[i]] = v.Int64
+			}
+		}
+		if len(cur) == 0 {
+			continue
+		}
+
+		deltas, ok := state.Deltas("hrsp/"+pxname+"/"+l.Svname, cur)
+		if ok {
+			ret[l.Svname] = deltas
+		}
+	}
+
+	return ret
+}
+
+// httpErrorThresholds makes thresholds for 4xx and 5xx ratio and rate since the previous run
+func httpErrorThresholds(deltas map[string]Counters, elapsed time.Duration, th *Thresholds) []metricThreshold {
+	ratio := func(name string) func(l haproxy.StatLine) (float32, bool) {
+		return func(l haproxy.StatLine) (float32, bool) {
+			d, ok := deltas[l.Svname]
+			if !ok {
+				return 0, false
+			}
+
+			var total int64
+			for _, v := range d {
+				total += v
+			}
+			if total == 0 || total < int64(th.HTTPMinResponses) {
+				return 0, false
+			}
+
+			return 100.0 * float32(d[name]) / float32(total), true
+		}
+	}
+
+	rate := func(name string) func(l haproxy.StatLine) (float32, bool) {
+		return func(l haproxy.StatLine) (float32, bool) {
+			d, ok := deltas[l.Svname]
+			if !ok || elapsed <= 0 {
+				return 0, false
+			}
+
+			return float32(float64(d[name]) / elapsed.Seconds()), true
+		}
+	}
+
+	return []metricThreshold{
+		{
+			Name:     "HTTP 5xx ratio",
+			Unit:     "%",
+			Value:    ratio("hrsp_5xx"),
+			Warning:  th.HTTP5xxWarningPercent,
+			Critical: th.HTTP5xxCriticalPercent,
+		},
+		{
+			Name:      "HTTP 5xx rate",
+			Unit:      "/s",
+			Precision: 2,
+			Value:     rate("hrsp_5xx"),
+			Warning:   th.HTTP5xxRateWarning,
+			Critical:  th.HTTP5xxRateCritical,
+		},
+		{
+			Name:     "HTTP 4xx ratio",
+			Unit:     "%",
+			Value:    ratio("hrsp_4xx"),
+			Warning:  th.HTTP4xxWarningPercent,
+			Critical: th.HTTP4xxCriticalPercent,
+		},
+		{
+			Name:      "HTTP 4xx rate",
+			Unit:      "/s",
+			Precision: 2,
+			Value:     rate("hrsp_4xx"),
+			Warning:   th.HTTP4xxRateWarning,
+			Critical:  th.HTTP4xxRateCritical,
+		},
+	}
+}
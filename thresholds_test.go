@@ -0,0 +1,143 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sardinasystems/sensu-go-haproxy-check/haproxy"
+)
+
+func TestCheckQueueThresholds(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{}
+
+	stats := testingStats(t)
+	svc := stats["ipmi_exporter"]
+
+	srv := svc["ctrl02"]
+	srv.Qcur = haproxy.NewInt64(40)
+	srv.Qlimit = haproxy.NewInt64(50)
+	srv.Qtime = haproxy.NewInt64(300)
+	svc["ctrl02"] = srv
+
+	bk := svc[haproxy.Backend]
+	bk.Qcur = haproxy.NewInt64(40)
+	bk.Qtime = haproxy.NewInt64(150)
+	svc[haproxy.Backend] = bk
+
+	// all disabled by default
+	assert.Equal(sensu.CheckStateOK, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, backendAndServers(svc), queueThresholds(&plugin.Thresholds)))
+
+	plugin.QueueWarning = 10
+	plugin.QueueCritical = 100
+	assert.Equal(sensu.CheckStateWarning, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, backendAndServers(svc), queueThresholds(&plugin.Thresholds)))
+
+	plugin.QueueCriticalPercent = 75
+	assert.Equal(sensu.CheckStateCritical, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, backendAndServers(svc), queueThresholds(&plugin.Thresholds)))
+
+	plugin = Config{Thresholds: Thresholds{QueueTimeWarning: 100, QueueTimeCritical: 200}}
+	assert.Equal(sensu.CheckStateCritical, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, backendAndServers(svc), queueThresholds(&plugin.Thresholds)))
+
+	plugin = Config{Thresholds: Thresholds{QueueTimeWarning: 100, QueueTimeCritical: 1000}}
+	status, err := checkService("ipmi_exporter", svc)
+	assert.NoError(err)
+	assert.Equal(sensu.CheckStateWarning, status)
+}
+
+func TestCheckTimeThresholds(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{}
+
+	stats := testingStats(t)
+	svc := stats["ipmi_exporter"]
+
+	// fixture: ctime is 0, rtime and ttime are 1752, 1742, 1642 and 1711 for BACKEND
+	assert.Equal(sensu.CheckStateOK, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, backendAndServers(svc), timeThresholds(&plugin.Thresholds)))
+
+	plugin.TotalTimeWarning = 1700
+	plugin.TotalTimeCritical = 1750
+	assert.Equal(sensu.CheckStateCritical, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, backendAndServers(svc), timeThresholds(&plugin.Thresholds)))
+
+	plugin.TotalTimeCritical = 2000
+	assert.Equal(sensu.CheckStateWarning, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, backendAndServers(svc), timeThresholds(&plugin.Thresholds)))
+
+	plugin = Config{Thresholds: Thresholds{ConnectTimeWarning: 1}}
+	assert.Equal(sensu.CheckStateOK, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, backendAndServers(svc), timeThresholds(&plugin.Thresholds)))
+
+	plugin = Config{Thresholds: Thresholds{ResponseTimeWarning: 1000, ResponseTimeCritical: 1745}}
+	assert.Equal(sensu.CheckStateCritical, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, backendAndServers(svc), timeThresholds(&plugin.Thresholds)))
+}
+
+func TestCheckHTTPErrorThresholds(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(saved Config) { plugin = saved }(plugin)
+	plugin = Config{
+		Thresholds: Thresholds{
+			HTTP5xxWarningPercent:  5,
+			HTTP5xxCriticalPercent: 20,
+			HTTPMinResponses:       10,
+		},
+	}
+
+	defer func(saved *StateStore) { state = saved }(state)
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Unix(1700000000, 0)
+
+	var err error
+	state, err = LoadState(path, now)
+	if !assert.NoError(err) {
+		return
+	}
+
+	svc := testingStats(t)["ipmi_exporter"]
+	assert.Equal(sensu.CheckStateOK, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, svc, httpErrorThresholds(httpDeltas("ipmi_exporter", svc), state.Elapsed(), &plugin.Thresholds)))
+	assert.NoError(state.Save())
+
+	// 10 of 100 new responses failed
+	state, err = LoadState(path, now.Add(time.Minute))
+	if !assert.NoError(err) {
+		return
+	}
+
+	srv := svc["ctrl01"]
+	srv.Hrsp2Xx = haproxy.NewInt64(srv.Hrsp2Xx.Int64 + 90)
+	srv.Hrsp5Xx = haproxy.NewInt64(srv.Hrsp5Xx.Int64 + 10)
+	svc["ctrl01"] = srv
+
+	deltas := httpDeltas("ipmi_exporter", svc)
+	assert.Equal(int64(10), deltas["ctrl01"]["hrsp_5xx"])
+	assert.Equal(sensu.CheckStateWarning, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, svc, httpErrorThresholds(deltas, state.Elapsed(), &plugin.Thresholds)))
+
+	plugin.HTTP5xxRateCritical = 0.1
+	assert.Equal(sensu.CheckStateCritical, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, svc, httpErrorThresholds(deltas, state.Elapsed(), &plugin.Thresholds)))
+
+	plugin.HTTP5xxRateCritical = 0
+	plugin.HTTPMinResponses = 1000
+	assert.Equal(sensu.CheckStateOK, checkThresholds(&ProxyReport{Pxname: "ipmi_exporter"}, svc, httpErrorThresholds(deltas, state.Elapsed(), &plugin.Thresholds)))
+	assert.NoError(state.Save())
+
+	// reload: counters went backwards, no deltas
+	state, err = LoadState(path, now.Add(2*time.Minute))
+	if !assert.NoError(err) {
+		return
+	}
+
+	srv.Hrsp2Xx = haproxy.NewInt64(1)
+	srv.Hrsp5Xx = haproxy.NewInt64(1)
+	svc["ctrl01"] = srv
+
+	deltas = httpDeltas("ipmi_exporter", svc)
+	assert.NotContains(deltas, "ctrl01")
+	assert.Contains(deltas, "ctrl02")
+	assert.Equal(1, state.Resets)
+}